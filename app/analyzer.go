@@ -17,71 +17,170 @@
 package app
 
 import (
+	"context"
 	"database/sql"
-	"encoding/csv"
 	"fmt"
-	"io"
 	"log/slog"
-	"strings"
+	"sync"
 	"time"
 
+	"github.com/anthropics/anthropic-sdk-go"
+
 	"git.cypr.io/oz/aguaxaca/app/db"
 	"git.cypr.io/oz/aguaxaca/parser"
 )
 
-// MaxRuns limits how many retries the analyzer performs on errors.
-const MaxRuns = 3
+// MaxRuns limits how many times the analyzer tries an import: after
+// that many failures, the import is dead. With RetryBackoff, the last
+// try is about a day after the first.
+const MaxRuns = 6
+
+// RetryDelay is the delay before analyzing a failed import again. It
+// doubles after each failure, up to MaxRetryDelay.
+const (
+	RetryDelay    = time.Hour
+	MaxRetryDelay = 24 * time.Hour
+)
 
 // DateFormat is the format of date fields in the LLM's output.
-const DateFormat = "2006-01-02"
+const DateFormat = parser.DateFormat
+
+// Outcomes of an analysis, stored with the parser's response.
+const (
+	OutcomeSuccess     = "success"
+	OutcomeParserError = "parser_error" // no usable response from the parser
+	OutcomeImportError = "import_error" // invalid, or unsaved, deliveries
+)
 
 type Analyzer struct {
-	app *App
-	log *slog.Logger
+	app       *App
+	parser    parser.Parser
+	prompt    string
+	validator *Validator
+	workers   int
+	limiter   *TokenBucket
+	log       *slog.Logger
 }
 
-func (app *App) NewAnalyzer() *Analyzer {
+func (app *App) NewAnalyzer(p parser.Parser) *Analyzer {
 	return &Analyzer{
-		app: app,
-		log: app.Logger,
+		app:       app,
+		parser:    p,
+		prompt:    parser.DefaultPrompt,
+		validator: NewValidator(),
+		workers:   app.Config.AnalyzeWorkers,
+		limiter:   NewTokenBucket(app.Config.AnalyzeRate, app.Config.AnalyzeWorkers),
+		log:       app.Logger,
+	}
+}
+
+// DefaultParser builds the parser selected in the config: "anthropic"
+// (the default), "openai" (any OpenAI-compatible API), or "fake" (canned
+// responses).
+func (app *App) DefaultParser() parser.Parser {
+	switch app.Config.Parser {
+	case "openai":
+		p := parser.NewOpenAIParser(app.Config.OpenAIBaseURL, app.Config.OpenAIModel)
+		p.APIKey = app.Config.OpenAIAPIKey
+		return p
+	case "fake":
+		return parser.NewFakeParser(app.Config.FakeParserDir)
+	default:
+		p := parser.NewAnthropicParser()
+		if model := app.Config.AnthropicModel; model != "" {
+			p.Model = anthropic.Model(model)
+		}
+		return p
 	}
 }
 
 func (a *Analyzer) ProcessPendingImports() (int, error) {
 	queries := db.New(a.app.DB)
 
-	runs := sql.NullInt64{Int64: MaxRuns, Valid: true}
-	imports, err := queries.GetPendingImports(a.app.Ctx, runs)
+	imports, err := queries.GetPendingImports(a.app.Ctx)
 	if err != nil {
 		return 0, err
 	}
 
+	return a.ProcessImports(imports)
+}
+
+// ImportFilter selects imports to analyze again: by ID, by publication
+// date (or creation date, when unknown), or failed imports.
+type ImportFilter struct {
+	ID     int64
+	Since  time.Time
+	Failed bool
+}
+
+// FindImports returns imports matching filter's first set field.
+func (a *Analyzer) FindImports(filter ImportFilter) ([]db.Import, error) {
+	queries := db.New(a.app.DB)
+	switch {
+	case filter.ID != 0:
+		im, err := queries.GetImport(a.app.Ctx, filter.ID)
+		if err != nil {
+			return nil, fmt.Errorf("import #%d: %w", filter.ID, err)
+		}
+		return []db.Import{im}, nil
+	case !filter.Since.IsZero():
+		return queries.ListImportsSince(a.app.Ctx, db.UnixTime{Time: filter.Since.UTC()})
+	case filter.Failed:
+		return queries.ListFailedImports(a.app.Ctx)
+	}
+	return nil, fmt.Errorf("empty import filter")
+}
+
+// Reanalyze resets the state of imports, and analyzes them again. Their
+// deliveries are updated once the new analysis succeeds (see
+// ImportData). With cached, the latest stored response of each import
+// is imported again instead of calling the parser.
+func (a *Analyzer) Reanalyze(imports []db.Import, cached bool) (int, error) {
+	if cached {
+		return a.reimport(imports)
+	}
+
+	queries := db.New(a.app.DB)
+	for i := range imports {
+		if err := queries.ResetImport(a.app.Ctx, imports[i].ID); err != nil {
+			return 0, fmt.Errorf("ResetImport error for #%d: %v", imports[i].ID, err)
+		}
+		imports[i].CompletedAt = nil
+		imports[i].FailedAt = nil
+		imports[i].Runs = sql.NullInt64{Int64: 0, Valid: true}
+	}
+	return a.ProcessImports(imports)
+}
+
+// reimport imports the latest stored response of each import again.
+func (a *Analyzer) reimport(imports []db.Import) (int, error) {
+	queries := db.New(a.app.DB)
 	imCount := 0
 	for _, im := range imports {
-		log := a.log.With("import", im.ID, "runs", im.Runs.Int64)
+		log := a.log.With("import", im.ID)
 
-		log.Info("analyzing image")
-		csvData, err := parser.ParseFileWithPrompt(a.app.Ctx, im.FilePath, parser.DefaultCSVPrompt)
+		analyses, err := queries.ListAnalysesByImport(a.app.Ctx, im.ID)
 		if err != nil {
-			log.Error("analyze error", "error", err.Error())
-
-			if dbErr := queries.FailImport(a.app.Ctx, im.ID); dbErr != nil {
-				return imCount, fmt.Errorf("FailImport error for #%d: %v", im.ID, dbErr)
+			return imCount, fmt.Errorf("ListAnalysesByImport error for #%d: %v", im.ID, err)
+		}
+		var latest *db.Analysis
+		for i := range analyses {
+			if analyses[i].Response != "" {
+				latest = &analyses[i]
+				break
 			}
+		}
+		if latest == nil {
+			log.Warn("no stored response (skipped)")
 			continue
 		}
 
-		log.Debug("importing data")
-		if err := a.ImportData(&im, csvData); err != nil {
+		log.Info("importing stored response", "analysis", latest.ID, "prompt", latest.PromptHash)
+		if err := a.ImportData(&im, latest.Response); err != nil {
 			log.Error("parser error", "error", err)
-
-			if dbErr := queries.FailImport(a.app.Ctx, im.ID); dbErr != nil {
-				return imCount, fmt.Errorf("FailImport error for #%d: %v", im.ID, dbErr)
-			}
 			continue
 		}
 
-		// Update import state
 		if err := queries.CompleteImport(a.app.Ctx, im.ID); err != nil {
 			return imCount, fmt.Errorf("Error updating DB (CompleteImport) for #%d: %v", im.ID, err)
 		}
@@ -91,43 +190,309 @@ func (a *Analyzer) ProcessPendingImports() (int, error) {
 	return imCount, nil
 }
 
-func (a *Analyzer) ImportData(im *db.Import, csvData string) error {
-	a.log.Debug("CSV data", "import", im.ID, "csv", csvData)
+// analysis is the parser's response for an import.
+type analysis struct {
+	im       db.Import
+	response *parser.Response
+	latency  time.Duration
+	err      error
+}
+
+// ProcessImports analyzes each import's image, and returns the number
+// of successful imports. Images are sent to the parser by a pool of
+// workers, within the rate limit, while results are stored one at a
+// time, as SQLite only handles a single writer. When the app's context
+// is canceled, no new analysis starts, and imports that were not
+// analyzed are left pending. Analyses in flight, already paid for, get
+// ShutdownGracePeriod to finish, and be stored.
+func (a *Analyzer) ProcessImports(imports []db.Import) (int, error) {
+	ctx, cancel := context.WithCancel(a.app.Ctx)
+	defer cancel()
+
+	// In-flight parser calls, and the storage of their results, outlive
+	// ctx by the grace period.
+	inflight, cancelInflight := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelInflight()
+	context.AfterFunc(ctx, func() {
+		timer := time.AfterFunc(ShutdownGracePeriod*time.Second, cancelInflight)
+		context.AfterFunc(inflight, func() { timer.Stop() })
+	})
+	store := a.withContext(inflight)
+
+	jobs := make(chan db.Import)
+	go func() {
+		defer close(jobs)
+		for _, im := range imports {
+			select {
+			case jobs <- im:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	results := make(chan analysis)
+	var wg sync.WaitGroup
+	for range min(a.workers, len(imports)) {
+		wg.Go(func() {
+			for im := range jobs {
+				results <- a.analyze(ctx, inflight, im)
+			}
+		})
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	imCount, done := 0, 0
+	var err error
+	for result := range results {
+		if err != nil {
+			continue // drain results after a DB error
+		}
+		if result.err != nil && ctx.Err() != nil {
+			continue // canceled analysis, left pending
+		}
 
+		var ok bool
+		ok, err = store.storeAnalysis(&result)
+		if err != nil {
+			cancel()
+			continue
+		}
+		if ok {
+			imCount += 1
+		}
+		done += 1
+		a.log.Info("analysis progress", "done", done, "total", len(imports), "imported", imCount)
+	}
+
+	if err == nil && a.app.Ctx.Err() != nil {
+		err = fmt.Errorf("analysis interrupted, %d imports left pending: %w", len(imports)-done, a.app.Ctx.Err())
+	}
+	return imCount, err
+}
+
+// analyze sends an import's image to the parser, once the rate limit
+// allows it: no call starts after ctx is canceled, but a call in flight
+// runs until inflight is.
+func (a *Analyzer) analyze(ctx context.Context, inflight context.Context, im db.Import) analysis {
+	if err := a.limiter.Wait(ctx); err != nil {
+		return analysis{im: im, err: err}
+	}
+
+	a.log.Info("analyzing image", "import", im.ID, "runs", im.Runs.Int64)
+	start := time.Now()
+	response, err := a.parser.ParseFile(inflight, im.FilePath, a.prompt)
+	return analysis{im: im, response: response, latency: time.Since(start), err: err}
+}
+
+// withContext returns a copy of the analyzer, whose DB queries run with
+// ctx instead of the app's context.
+func (a *Analyzer) withContext(ctx context.Context) *Analyzer {
+	app := *a.app
+	app.Ctx = ctx
+	b := *a
+	b.app = &app
+	return &b
+}
+
+// storeAnalysis imports the deliveries of a parser's response, and
+// updates the import's state. It returns false when the analysis
+// failed, and an error when the DB couldn't be updated.
+func (a *Analyzer) storeAnalysis(result *analysis) (bool, error) {
+	im := &result.im
+	log := a.log.With("import", im.ID, "runs", im.Runs.Int64)
 	queries := db.New(a.app.DB)
-	reader := csv.NewReader(strings.NewReader(csvData))
 
-	// Read header row
-	_, err := reader.Read()
+	if result.err != nil {
+		log.Error("analyze error", "error", result.err.Error())
+
+		if dbErr := a.saveAnalysis(im, result.response, result.latency, OutcomeParserError, result.err); dbErr != nil {
+			return false, dbErr
+		}
+		return false, a.failImport(im, result.err)
+	}
+
+	log.Debug("importing data")
+	if err := a.ImportData(im, result.response.Text); err != nil {
+		log.Error("parser error", "error", err)
+
+		if dbErr := a.saveAnalysis(im, result.response, result.latency, OutcomeImportError, err); dbErr != nil {
+			return false, dbErr
+		}
+		return false, a.failImport(im, err)
+	}
+
+	// Update import state
+	if err := a.saveAnalysis(im, result.response, result.latency, OutcomeSuccess, nil); err != nil {
+		return false, err
+	}
+	if err := queries.CompleteImport(a.app.Ctx, im.ID); err != nil {
+		return false, fmt.Errorf("Error updating DB (CompleteImport) for #%d: %v", im.ID, err)
+	}
+	return true, nil
+}
+
+// failImport records the error of an import's analysis, and when to try
+// again. After MaxRuns failures, the import is dead instead.
+func (a *Analyzer) failImport(im *db.Import, cause error) error {
+	runs := im.Runs.Int64 + 1
+	now := time.Now().UTC()
+	params := db.FailImportParams{ID: im.ID, LastError: cause.Error()}
+	if runs >= MaxRuns {
+		params.DeadAt = &db.UnixTime{Time: now}
+		a.log.Warn("dead import", "import", im.ID, "runs", runs)
+	} else {
+		params.NextAttemptAt = &db.UnixTime{Time: now.Add(RetryBackoff(runs))}
+	}
+
+	if err := db.New(a.app.DB).FailImport(a.app.Ctx, params); err != nil {
+		return fmt.Errorf("FailImport error for #%d: %v", im.ID, err)
+	}
+	return nil
+}
+
+// RetryBackoff is the delay before trying an import again, after it
+// failed runs times: RetryDelay, doubled after each failure.
+func RetryBackoff(runs int64) time.Duration {
+	delay := RetryDelay
+	for i := int64(1); i < runs && delay < MaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, MaxRetryDelay)
+}
+
+// saveAnalysis records a parser run, and its outcome. The response is
+// nil when the parser failed without an answer from the model.
+func (a *Analyzer) saveAnalysis(im *db.Import, response *parser.Response, latency time.Duration, outcome string, err error) error {
+	params := db.CreateAnalysisParams{
+		ImportID:   im.ID,
+		PromptHash: parser.PromptHash(a.prompt),
+		LatencyMs:  latency.Milliseconds(),
+		Outcome:    outcome,
+	}
+	if response != nil {
+		params.Model = response.Model
+		params.Response = response.Text
+		params.InputTokens = response.InputTokens
+		params.OutputTokens = response.OutputTokens
+	}
+	if err != nil {
+		params.Error = err.Error()
+	}
+
+	if _, dbErr := db.New(a.app.DB).CreateAnalysis(a.app.Ctx, params); dbErr != nil {
+		return fmt.Errorf("CreateAnalysis error for #%d: %v", im.ID, dbErr)
+	}
+	return nil
+}
+
+// ImportData decodes a parser's response (JSON, or legacy CSV), and
+// replaces the import's deliveries with the ones it contains, in a
+// single transaction: nothing is stored if the response is invalid.
+// Deliveries that fail validation are quarantined.
+//
+// Deliveries of a previous analysis are updated in place, to keep their
+// IDs, and reviewed ones are kept as is: extractions matching what a
+// reviewer approved, added, edited, or deleted are ignored.
+func (a *Analyzer) ImportData(im *db.Import, response string) error {
+	a.log.Debug("parser response", "import", im.ID, "response", response)
+
+	deliveries, err := parser.DecodeDeliveries(response)
 	if err != nil {
-		return fmt.Errorf("error reading CSV header: %w", err)
+		return err
 	}
 
-	for {
-		record, err := reader.Read()
+	tx, err := a.app.DB.BeginTx(a.app.Ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queries := db.New(a.app.DB).WithTx(tx)
+	matcher, err := LoadMatcher(a.app, queries)
+	if err != nil {
+		return err
+	}
+
+	existing, err := queries.ListDeliveriesByImport(a.app.Ctx, sql.NullInt64{Int64: im.ID, Valid: true})
+	if err != nil {
+		return fmt.Errorf("failed to list previous deliveries: %w", err)
+	}
+	reviewed, err := a.app.reviewedKeys(queries, im.ID, existing)
+	if err != nil {
+		return err
+	}
+
+	// Unreviewed deliveries of the previous analysis, by extraction key.
+	previous := map[string][]int64{}
+	for _, d := range existing {
+		if d.ApprovedAt == nil {
+			key := deliveryKey(d)
+			previous[key] = append(previous[key], d.ID)
+		}
+	}
+	if err := queries.DeleteQuarantineByImport(a.app.Ctx, im.ID); err != nil {
+		return fmt.Errorf("failed to delete previous quarantine: %w", err)
+	}
+
+	for _, delivery := range deliveries {
+		key := extractionKey(delivery.Date, delivery.Schedule, delivery.LocationName)
+		if reviewed[key] {
+			a.log.Debug("kept reviewed delivery", "import", im.ID, "location_name", delivery.LocationName)
+			continue
+		}
+
+		reason := ""
+		c, err := newCandidate(delivery)
 		if err != nil {
-			if err == io.EOF {
-				break
+			reason = err.Error()
+		} else {
+			reason = a.validator.Check(im, len(deliveries), c)
+		}
+
+		if reason != "" {
+			a.log.Warn("quarantined delivery", "import", im.ID, "location_name", delivery.LocationName, "reason", reason)
+			_, err := queries.QuarantineDelivery(a.app.Ctx, db.QuarantineDeliveryParams{
+				ImportID:     im.ID,
+				Date:         delivery.Date,
+				Schedule:     delivery.Schedule,
+				LocationType: delivery.LocationType,
+				LocationName: delivery.LocationName,
+				Notes:        delivery.Notes,
+				Reason:       reason,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to quarantine delivery: %w", err)
 			}
-			return fmt.Errorf("error reading CSV record: %w", err)
+			continue
 		}
 
-		date, err := time.Parse(DateFormat, record[0])
-		if err != nil {
-			return fmt.Errorf("invalid date format '%s': %w", record[0], err)
+		if ids := previous[key]; len(ids) > 0 {
+			previous[key] = ids[1:]
+			if _, err := a.app.updateDelivery(queries, matcher, ids[0], im.ID, c); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := a.app.createDelivery(queries, matcher, im.ID, c); err != nil {
+			return err
 		}
+	}
 
-		// Create delivery record with lowercase location_type
-		_, err = queries.CreateDelivery(a.app.Ctx, db.CreateDeliveryParams{
-			Date:         db.UnixTime{Time: date.UTC()},
-			Schedule:     strings.ToLower(record[1]),
-			LocationType: strings.ToLower(record[2]), // Ensure lowercase
-			LocationName: record[3],
-		})
-		if err != nil {
-			return fmt.Errorf("failed to create delivery: %w", err)
+	// Unreviewed deliveries missing from this analysis.
+	for _, ids := range previous {
+		for _, id := range ids {
+			if err := queries.DeleteDelivery(a.app.Ctx, id); err != nil {
+				return fmt.Errorf("failed to delete previous delivery #%d: %w", id, err)
+			}
 		}
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit deliveries: %w", err)
+	}
 	return nil
 }
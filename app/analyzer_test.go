@@ -0,0 +1,149 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"database/sql"
+	"log/slog"
+	"testing"
+
+	"git.cypr.io/oz/aguaxaca/app/db"
+	"git.cypr.io/oz/aguaxaca/parser"
+)
+
+// newTestApp runs the app with an empty DB.
+func newTestApp(t *testing.T) *App {
+	t.Helper()
+	a := NewApp(t.Context())
+	a.Logger = slog.New(slog.DiscardHandler)
+	a.Config.DataDir = t.TempDir()
+	if err := a.Init(); err != nil {
+		t.Fatalf("app init: %v", err)
+	}
+	t.Cleanup(func() { a.DB.Close() })
+	return a
+}
+
+func TestReanalyzeKeepsReviewedDeliveries(t *testing.T) {
+	a := newTestApp(t)
+	queries := db.New(a.DB)
+	analyzer := a.NewAnalyzer(parser.NewFakeParser(""))
+	review := a.NewReview()
+
+	im, err := queries.CreateImport(a.Ctx, db.CreateImportParams{FilePath: "test.png", FileHash: 1, Source: "test"})
+	if err != nil {
+		t.Fatalf("CreateImport: %v", err)
+	}
+	if n, err := analyzer.ProcessImports([]db.Import{im}); err != nil || n != 1 {
+		t.Fatalf("ProcessImports() = %d, %v", n, err)
+	}
+	deliveries := listDeliveries(t, a, im.ID)
+	if len(deliveries) != 5 {
+		t.Fatalf("got %d deliveries, want 5", len(deliveries))
+	}
+
+	// Approve the first delivery, edit the second, delete the third, and
+	// add one.
+	approved, edited, deleted := deliveries[0], deliveries[1], deliveries[2]
+	if err := review.ApproveDelivery("tester", approved.ID); err != nil {
+		t.Fatalf("ApproveDelivery: %v", err)
+	}
+	if _, err := review.UpdateDelivery("tester", edited.ID, parser.Delivery{
+		Date:         "2025-07-22",
+		Schedule:     "nocturno",
+		LocationType: "colonia",
+		LocationName: "Jardín (sector Bugambilias)",
+	}); err != nil {
+		t.Fatalf("UpdateDelivery: %v", err)
+	}
+	if err := review.DeleteDelivery("tester", deleted.ID); err != nil {
+		t.Fatalf("DeleteDelivery: %v", err)
+	}
+	added, err := review.AddDelivery("tester", im.ID, parser.Delivery{
+		Date:         "2025-07-21",
+		Schedule:     "matutino",
+		LocationType: "colonia",
+		LocationName: "Reforma",
+	})
+	if err != nil {
+		t.Fatalf("AddDelivery: %v", err)
+	}
+
+	im, err = queries.GetImport(a.Ctx, im.ID)
+	if err != nil {
+		t.Fatalf("GetImport: %v", err)
+	}
+	if n, err := analyzer.Reanalyze([]db.Import{im}, false); err != nil || n != 1 {
+		t.Fatalf("Reanalyze() = %d, %v", n, err)
+	}
+
+	// Reviewed deliveries are left as is, deleted ones don't come back,
+	// and the others keep their IDs.
+	got := map[int64]db.Delivery{}
+	for _, d := range listDeliveries(t, a, im.ID) {
+		got[d.ID] = d
+	}
+	want := []int64{approved.ID, edited.ID, deliveries[3].ID, deliveries[4].ID, added.ID}
+	if len(got) != len(want) {
+		t.Errorf("got %d deliveries after Reanalyze, want %d", len(got), len(want))
+	}
+	for _, id := range want {
+		if _, ok := got[id]; !ok {
+			t.Errorf("delivery #%d is missing after Reanalyze", id)
+		}
+	}
+	if d := got[approved.ID]; d.ApprovedAt == nil {
+		t.Errorf("delivery #%d is not approved anymore", approved.ID)
+	}
+	for _, id := range []int64{deliveries[3].ID, deliveries[4].ID} {
+		if d := got[id]; d.ApprovedAt != nil {
+			t.Errorf("delivery #%d was approved by Reanalyze", id)
+		}
+	}
+	if d := got[edited.ID]; d.ApprovedAt == nil {
+		t.Errorf("edited delivery #%d is not approved", edited.ID)
+	}
+	if d := got[edited.ID]; d.Schedule != "nocturno" || d.Date.Time.Format(DateFormat) != "2025-07-22" {
+		t.Errorf("edited delivery #%d was replaced: %s %s", edited.ID, d.Date.Time.Format(DateFormat), d.Schedule)
+	}
+
+	// Audit entries still point to existing deliveries.
+	entries, err := queries.ListAuditLogByImport(a.Ctx, im.ID)
+	if err != nil {
+		t.Fatalf("ListAuditLogByImport: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Errorf("got %d audit entries, want 4", len(entries))
+	}
+	for _, e := range entries {
+		if e.Action == ActionDelete {
+			continue
+		}
+		if _, ok := got[e.DeliveryID.Int64]; !ok {
+			t.Errorf("audit entry #%d (%s) points to missing delivery #%d", e.ID, e.Action, e.DeliveryID.Int64)
+		}
+	}
+}
+
+func listDeliveries(t *testing.T, a *App, importID int64) []db.Delivery {
+	t.Helper()
+	deliveries, err := db.New(a.DB).ListDeliveriesByImport(a.Ctx, sql.NullInt64{Int64: importID, Valid: true})
+	if err != nil {
+		t.Fatalf("ListDeliveriesByImport: %v", err)
+	}
+	return deliveries
+}
@@ -19,7 +19,6 @@ package app
 import (
 	"context"
 	"database/sql"
-	_ "embed"
 	"fmt"
 	"log/slog"
 	"os"
@@ -44,15 +43,14 @@ type Component interface {
 // ShutdownGracePeriod allows 10 seconds for graceful shutdowns.
 const ShutdownGracePeriod = 10
 
-//go:embed sql/schema.sql
-var ddl string
-
 type App struct {
-	DB         *sql.DB
-	Ctx        context.Context
-	Logger     *slog.Logger
-	ListenAddr string
-	Debug      bool
+	DB     *sql.DB
+	Ctx    context.Context
+	Logger *slog.Logger
+	Config Config
+
+	// AutoMigrate applies pending migrations when the DB is opened.
+	AutoMigrate bool
 }
 
 // NewApp builds the core App type.
@@ -60,18 +58,24 @@ func NewApp(ctx context.Context) *App {
 	app := new(App)
 	app.Ctx = ctx
 	app.Logger = slog.Default()
-	app.Debug = false
+	app.Config = DefaultConfig()
+	app.AutoMigrate = true
 
 	return app
 }
 
-// Init starts the app: connect DB handles, etc.
-func (app *App) Init(debug bool, listenAddr string) error {
-	app.ListenAddr = listenAddr
-	app.Debug = debug
+// Init starts the app: check its config, connect DB handles, etc.
+func (app *App) Init() error {
+	app.Config.Resolve()
+	if err := app.Config.Validate(); err != nil {
+		return fmt.Errorf("invalid config:\n%v", err)
+	}
+	if err := os.MkdirAll(app.Config.DataDir, 0750); err != nil {
+		return fmt.Errorf("creating data dir failed: %v", err)
+	}
 
 	// debug mode: use a new logger with lower level.
-	if debug {
+	if app.Config.Debug {
 		opts := &slog.HandlerOptions{Level: slog.LevelDebug}
 		app.Logger = slog.New(slog.NewTextHandler(os.Stdout, opts))
 	}
@@ -80,16 +84,19 @@ func (app *App) Init(debug bool, listenAddr string) error {
 }
 
 func (app *App) InitDB() error {
-	// TODO: configurable path to SQLite DB
-	db, err := sql.Open("sqlite", "agua.db")
+	db, err := sql.Open("sqlite", app.Config.DBPath)
 	if err != nil {
 		return fmt.Errorf("opening DB failed: %v", err)
 	}
 	app.DB = db
 
-	// Run schema.sql: create tables, indexes, etc.
-	if _, err := app.DB.ExecContext(app.Ctx, ddl); err != nil {
-		return fmt.Errorf("syncing schema failed: %v", err)
+	if !app.AutoMigrate {
+		return nil
+	}
+
+	// Apply pending migrations: create tables, indexes, etc.
+	if _, err := app.NewMigrator().Up(); err != nil {
+		return fmt.Errorf("migrating schema failed: %v", err)
 	}
 	return nil
 }
@@ -124,13 +131,16 @@ func (app *App) Start(components ...Component) error {
 	select {
 	case sig := <-sigChan:
 		app.Logger.Info("shutdown", "signal", sig)
+	case <-app.Ctx.Done():
+		app.Logger.Info("shutdown", "reason", context.Cause(app.Ctx))
 	case err := <-errChan:
 		app.Logger.Error("shutdown", "error", err)
 	}
 
 	// Shutdown components.
 	cancel()
-	shutdownCtx, shutdownCancel := context.WithTimeout(app.Ctx, ShutdownGracePeriod*time.Second)
+	// app.Ctx may be canceled already, by the same signal.
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.WithoutCancel(app.Ctx), ShutdownGracePeriod*time.Second)
 	defer shutdownCancel()
 
 	var shutdownWg sync.WaitGroup
@@ -21,6 +21,7 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"time"
 
 	"github.com/dchest/siphash"
 
@@ -40,68 +41,152 @@ type Collector struct {
 	log       *slog.Logger
 }
 
+// DefaultCollector builds the collector selected in the config:
+// "nitter" (HTML timeline, the default), "nitter-rss", or "inbox"
+// (images dropped in the inbox directory).
 func (app *App) DefaultCollector() *Collector {
-	account := os.Getenv("NITTER_ACCOUNT")
-	if account == "" {
-		account = "SOAPA_Oax"
+	var impl collector.Collector
+	name := app.Config.Collector
+	switch name {
+	case "inbox":
+		inbox := collector.NewInboxCollector(app.Config.InboxDir)
+		inbox.DownloadDir = app.Config.ImageDir
+		inbox.Log = app.Logger
+		impl = inbox
+	case "nitter-rss":
+		rss := collector.NewNitterRSSCollector(app.Config.NitterAccount)
+		app.configureNitter(&rss.NitterCollector)
+		impl = rss
+	default:
+		name = "nitter"
+		nitter := collector.NewNitterCollector(app.Config.NitterAccount)
+		app.configureNitter(nitter)
+		impl = nitter
 	}
-	nitter := collector.NewNitterCollector(account)
 
-	if nitterHost := os.Getenv("NITTER_HOST"); nitterHost != "" {
-		nitter.BaseDomain = nitterHost
-	}
+	return app.NewCollector(impl, name)
+}
+
+// NewFilesCollector builds a collector for image files, or directories
+// of images, stored in the image directory.
+func (app *App) NewFilesCollector(paths []string) *collector.FilesCollector {
+	files := collector.NewFilesCollector(paths)
+	files.DownloadDir = app.Config.ImageDir
+	files.Log = app.Logger
+	return files
+}
+
+func (app *App) configureNitter(nitter *collector.NitterCollector) {
+	nitter.BaseDomain = app.Config.NitterHost
+	nitter.DownloadDir = app.Config.ImageDir
+	nitter.CacheDir = app.Config.CacheDir
 	nitter.Log = app.Logger
+}
 
+// NewCollector wraps any collector implementation, named for logs.
+func (app *App) NewCollector(impl collector.Collector, name string) *Collector {
 	return &Collector{
 		app:       app,
-		collector: nitter,
-		log:       app.Logger.With("collector", "nitter"),
+		collector: impl,
+		log:       app.Logger.With("collector", name),
 	}
 }
 
 // Collect runs an image collector to fetch images, and create import
 // records in the local DB.
 func (c *Collector) Collect() error {
-	images, err := c.collector.DownloadImages()
+	_, err := c.CollectImports()
+	return err
+}
+
+// CollectImports is like Collect, and returns the new imports.
+func (c *Collector) CollectImports() ([]db.Import, error) {
+	items, err := c.collector.DownloadImages()
+	if err != nil {
+		return nil, fmt.Errorf("image download: %v", err)
+	}
+	return c.createImports(items), nil
+}
+
+// Backfill runs the collector on older posts, published after since,
+// when it supports it, and create import records in the local DB.
+func (c *Collector) Backfill(since time.Time, maxPages int) error {
+	backfiller, ok := c.collector.(collector.Backfiller)
+	if !ok {
+		return fmt.Errorf("collector does not support backfilling")
+	}
+
+	items, err := backfiller.Backfill(since, maxPages)
 	if err != nil {
 		return fmt.Errorf("image download: %v", err)
 	}
-	c.log.Debug("importable images", "images", images)
-
-	// Create import jobs for each new image.
-	for _, path := range images {
-		fileHash, err := hashFile(path)
-		if err != nil {
-			c.log.Error("hash error", "path", path, "error", err)
-			continue
-		}
+	c.createImports(items)
+	return nil
+}
 
-		if err := c.CreateImportIfNotExists(path, int64(fileHash)); err != nil {
-			c.log.Error("import error", "path", path, "error", err)
-			continue
+// createImports creates import jobs for each new image, and returns them.
+func (c *Collector) createImports(items []collector.Item) []db.Import {
+	c.log.Debug("importable posts", "items", items)
+	imports := []db.Import{}
+
+	for _, item := range items {
+		for _, path := range item.Images {
+			fileHash, err := hashFile(path)
+			if err != nil {
+				c.log.Error("hash error", "path", path, "error", err)
+				continue
+			}
+
+			params := newImportParams(&item, path, int64(fileHash))
+			imp, err := c.CreateImportIfNotExists(params)
+			if err != nil {
+				c.log.Error("import error", "path", path, "error", err)
+				continue
+			}
+			if imp != nil {
+				imports = append(imports, *imp)
+			}
 		}
 	}
-	return nil
+	return imports
 }
 
-func (c *Collector) CreateImportIfNotExists(path string, hash int64) error {
+// CreateImportIfNotExists creates an import, unless an image with the
+// same hash was already imported (then it returns nil).
+func (c *Collector) CreateImportIfNotExists(params db.CreateImportParams) (*db.Import, error) {
 	queries := db.New(c.app.DB)
-	count, err := queries.CountImportsByHash(c.app.Ctx, hash)
+	count, err := queries.CountImportsByHash(c.app.Ctx, params.FileHash)
 	if err != nil {
-		return fmt.Errorf("CountImportsByHash for '%s': %v", path, err)
+		return nil, fmt.Errorf("CountImportsByHash for '%s': %v", params.FilePath, err)
 	}
 	if count != 0 {
-		c.log.Info("already collected (skipped)", "path", path)
-		return nil
+		c.log.Info("already collected (skipped)", "path", params.FilePath)
+		return nil, nil
 	}
 
-	imp, err := queries.CreateImport(c.app.Ctx, db.CreateImportParams{FilePath: path, FileHash: hash})
+	imp, err := queries.CreateImport(c.app.Ctx, params)
 	if err != nil {
-		return fmt.Errorf("CreateImport for '%s': %v", path, err)
+		return nil, fmt.Errorf("CreateImport for '%s': %v", params.FilePath, err)
 	}
-	c.log.Info("new import job", "job", imp.ID, "path", imp.FilePath)
+	c.log.Info("new import job", "job", imp.ID, "path", imp.FilePath, "post", imp.PostUrl)
 
-	return nil
+	return &imp, nil
+}
+
+// newImportParams describes an image downloaded from a collected item.
+func newImportParams(item *collector.Item, path string, hash int64) db.CreateImportParams {
+	params := db.CreateImportParams{
+		FilePath: path,
+		FileHash: hash,
+		Source:   item.Source,
+		PostID:   item.PostID,
+		PostUrl:  item.URL,
+		PostText: item.Text,
+	}
+	if !item.PostedAt.IsZero() {
+		params.PostedAt = &db.UnixTime{Time: item.PostedAt.UTC()}
+	}
+	return params
 }
 
 // hashFile uses siphash for deduplication.
@@ -0,0 +1,286 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/peterbourgon/ff/v3"
+
+	"git.cypr.io/oz/aguaxaca/collector"
+	"git.cypr.io/oz/aguaxaca/parser"
+)
+
+// EnvVarPrefix prefixes the env. variables of settings: the "db-path"
+// flag is read from AGUAXACA_DB_PATH.
+const EnvVarPrefix = "AGUAXACA"
+
+// Collectors, and Parsers, are the known values of the "collector", and
+// "parser" settings.
+var (
+	Collectors = []string{"nitter", "nitter-rss", "inbox"}
+	Parsers    = []string{"anthropic", "openai", "fake"}
+)
+
+// Default concurrency of the analyzer, and limit of parser requests per
+// minute.
+const (
+	DefaultAnalyzeWorkers = 4
+	DefaultAnalyzeRate    = 50
+)
+
+// Default intervals of the cron scheduler.
+const (
+	DefaultCollectInterval      = time.Hour
+	DefaultCollectorGracePeriod = 12 * time.Hour
+)
+
+// Config holds the settings of the app. Paths left empty are resolved
+// relative to DataDir.
+type Config struct {
+	Debug      bool
+	ListenAddr string
+
+	// Storage
+	DataDir  string
+	DBPath   string
+	ImageDir string
+	CacheDir string
+	InboxDir string
+
+	// Collector
+	Collector     string
+	NitterHost    string
+	NitterAccount string
+
+	// Parser
+	Parser         string
+	AnthropicModel string
+	OpenAIBaseURL  string
+	OpenAIModel    string
+	OpenAIAPIKey   string
+	FakeParserDir  string
+
+	// Analysis
+	AnalyzeWorkers int
+	AnalyzeRate    int
+
+	// Scheduler
+	CollectInterval      time.Duration
+	CollectorGracePeriod time.Duration
+
+	// Web review pages
+	AdminUser     string
+	AdminPassword string
+}
+
+// DefaultConfig returns the settings used when nothing is configured.
+func DefaultConfig() Config {
+	return Config{
+		ListenAddr:           "localhost:8080",
+		DataDir:              ".",
+		Collector:            "nitter",
+		NitterHost:           collector.DefaultBaseDomain,
+		NitterAccount:        "SOAPA_Oax",
+		Parser:               "anthropic",
+		OpenAIBaseURL:        parser.DefaultOpenAIBaseURL,
+		AnalyzeWorkers:       DefaultAnalyzeWorkers,
+		AnalyzeRate:          DefaultAnalyzeRate,
+		CollectInterval:      DefaultCollectInterval,
+		CollectorGracePeriod: DefaultCollectorGracePeriod,
+		AdminUser:            "admin",
+	}
+}
+
+// RegisterFlags defines a flag for each setting in fs, with the current
+// values as defaults.
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.Debug, "debug", c.Debug, "log debug information")
+	fs.StringVar(&c.ListenAddr, "listen", c.ListenAddr, "listen address")
+
+	fs.StringVar(&c.DataDir, "data-dir", c.DataDir, "where data is stored")
+	fs.StringVar(&c.DBPath, "db-path", c.DBPath, "SQLite DB file (default: DATA_DIR/agua.db)")
+	fs.StringVar(&c.ImageDir, "image-dir", c.ImageDir, "where images are stored (default: DATA_DIR/images)")
+	fs.StringVar(&c.CacheDir, "cache-dir", c.CacheDir, "HTTP cache of the collector (default: DATA_DIR/cache)")
+	fs.StringVar(&c.InboxDir, "inbox-dir", c.InboxDir, "images dropped for the inbox collector (default: DATA_DIR/inbox)")
+
+	fs.StringVar(&c.Collector, "collector", c.Collector, "image collector: nitter, nitter-rss, or inbox")
+	fs.StringVar(&c.NitterHost, "nitter-host", c.NitterHost, "Nitter instance where posts are fetched")
+	fs.StringVar(&c.NitterAccount, "nitter-account", c.NitterAccount, "Twitter/X handle of the publisher")
+
+	fs.StringVar(&c.Parser, "parser", c.Parser, "image parser: anthropic, openai, or fake")
+	fs.StringVar(&c.AnthropicModel, "anthropic-model", c.AnthropicModel, "Anthropic model (default: the parser's)")
+	fs.StringVar(&c.OpenAIBaseURL, "openai-base-url", c.OpenAIBaseURL, "OpenAI-compatible API")
+	fs.StringVar(&c.OpenAIModel, "openai-model", c.OpenAIModel, "OpenAI-compatible model that reads images")
+	fs.StringVar(&c.OpenAIAPIKey, "openai-api-key", c.OpenAIAPIKey, "OpenAI-compatible API key, if required")
+	fs.StringVar(&c.FakeParserDir, "fake-parser-dir", c.FakeParserDir, "canned responses of the fake parser")
+
+	fs.IntVar(&c.AnalyzeWorkers, "analyze-workers", c.AnalyzeWorkers, "number of images analyzed concurrently")
+	fs.IntVar(&c.AnalyzeRate, "analyze-rate", c.AnalyzeRate, "maximum parser requests per minute (0: no limit)")
+
+	fs.DurationVar(&c.CollectInterval, "collect-interval", c.CollectInterval, "how often the scheduler runs the collector")
+	fs.DurationVar(&c.CollectorGracePeriod, "collector-grace-period", c.CollectorGracePeriod, "delay after the latest import, before collecting again")
+
+	fs.StringVar(&c.AdminUser, "admin-user", c.AdminUser, "login of the review pages")
+	fs.StringVar(&c.AdminPassword, "admin-password", c.AdminPassword, "password of the review pages (disabled when empty)")
+}
+
+// FlagOptions configures ff to read settings from flags, then AGUAXACA_*
+// env. variables, then the optional config file given with --config.
+func FlagOptions() []ff.Option {
+	return []ff.Option{
+		ff.WithEnvVarPrefix(EnvVarPrefix),
+		ff.WithConfigFileFlag("config"),
+		ff.WithConfigFileParser(ff.PlainParser),
+	}
+}
+
+// legacyEnvVars maps the env. variables of previous versions to flags.
+var legacyEnvVars = map[string]string{
+	"PARSER":          "parser",
+	"ANTHROPIC_MODEL": "anthropic-model",
+	"OPENAI_BASE_URL": "openai-base-url",
+	"OPENAI_MODEL":    "openai-model",
+	"OPENAI_API_KEY":  "openai-api-key",
+	"FAKE_PARSER_DIR": "fake-parser-dir",
+	"COLLECTOR":       "collector",
+	"NITTER_HOST":     "nitter-host",
+	"NITTER_ACCOUNT":  "nitter-account",
+	"INBOX_DIR":       "inbox-dir",
+	"ADMIN_USER":      "admin-user",
+	"ADMIN_PASSWORD":  "admin-password",
+}
+
+// LoadLegacyEnv uses the env. variables of previous versions as defaults
+// of fs's flags, so that flags, AGUAXACA_* variables, and the config file
+// still override them. Call it before parsing fs.
+func LoadLegacyEnv(fs *flag.FlagSet, log *slog.Logger) error {
+	for name, flagName := range legacyEnvVars {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		f := fs.Lookup(flagName)
+		if f == nil {
+			continue
+		}
+		if err := f.Value.Set(value); err != nil {
+			return fmt.Errorf("invalid %s: %v", name, err)
+		}
+		log.Warn("deprecated env. variable", "name", name, "use", envVarName(flagName))
+	}
+	return nil
+}
+
+// envVarName is the env. variable of a flag, as ff names it.
+func envVarName(flagName string) string {
+	return EnvVarPrefix + "_" + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// Resolve fills the paths left empty, relative to DataDir.
+func (c *Config) Resolve() {
+	if c.DBPath == "" {
+		c.DBPath = filepath.Join(c.DataDir, "agua.db")
+	}
+	if c.ImageDir == "" {
+		c.ImageDir = filepath.Join(c.DataDir, filepath.Base(collector.DefaultDownloadDir))
+	}
+	if c.CacheDir == "" {
+		c.CacheDir = filepath.Join(c.DataDir, filepath.Base(collector.DefaultCacheDir))
+	}
+	if c.InboxDir == "" {
+		c.InboxDir = filepath.Join(c.DataDir, filepath.Base(collector.DefaultInboxDir))
+	}
+}
+
+// Validate returns all the invalid settings of a resolved config.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.ListenAddr == "" {
+		invalid("listen: address is empty")
+	}
+
+	if c.DataDir == "" {
+		invalid("data-dir: path is empty")
+	} else if info, err := os.Stat(c.DataDir); err != nil && !os.IsNotExist(err) {
+		invalid("data-dir: %v", err)
+	} else if err == nil && !info.IsDir() {
+		invalid("data-dir: %s is not a directory", c.DataDir)
+	}
+	dirs := [][2]string{{"image-dir", c.ImageDir}, {"cache-dir", c.CacheDir}, {"inbox-dir", c.InboxDir}}
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir[1]); err == nil && !info.IsDir() {
+			invalid("%s: %s is not a directory", dir[0], dir[1])
+		}
+	}
+	if info, err := os.Stat(c.DBPath); err == nil && info.IsDir() {
+		invalid("db-path: %s is a directory", c.DBPath)
+	}
+
+	if !slices.Contains(Collectors, c.Collector) {
+		invalid("collector: unknown collector '%s', expected one of %v", c.Collector, Collectors)
+	}
+	if u, err := url.Parse(c.NitterHost); err != nil || u.Scheme == "" || u.Host == "" {
+		invalid("nitter-host: invalid URL '%s'", c.NitterHost)
+	}
+	if c.NitterAccount == "" {
+		invalid("nitter-account: account is empty")
+	}
+
+	if !slices.Contains(Parsers, c.Parser) {
+		invalid("parser: unknown parser '%s', expected one of %v", c.Parser, Parsers)
+	}
+	if u, err := url.Parse(c.OpenAIBaseURL); c.Parser == "openai" && (err != nil || u.Scheme == "" || u.Host == "") {
+		invalid("openai-base-url: invalid URL '%s'", c.OpenAIBaseURL)
+	}
+	if c.Parser == "openai" && c.OpenAIModel == "" {
+		invalid("openai-model: model is empty")
+	}
+
+	if c.AnalyzeWorkers < 1 {
+		invalid("analyze-workers: expected at least 1 worker, got %d", c.AnalyzeWorkers)
+	}
+	if c.AnalyzeRate < 0 {
+		invalid("analyze-rate: %d is a negative rate", c.AnalyzeRate)
+	}
+
+	if c.CollectInterval <= 0 {
+		invalid("collect-interval: %v is not a positive duration", c.CollectInterval)
+	}
+	if c.CollectorGracePeriod < 0 {
+		invalid("collector-grace-period: %v is a negative duration", c.CollectorGracePeriod)
+	}
+
+	if c.AdminPassword != "" && c.AdminUser == "" {
+		invalid("admin-user: login is empty")
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,58 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(c *Config)
+		wantErr string
+	}{
+		{"defaults", func(c *Config) {}, ""},
+		{"unknown parser", func(c *Config) { c.Parser = "ocr" }, "parser: unknown parser"},
+		{"openai", func(c *Config) { c.Parser, c.OpenAIModel = "openai", "gpt-4o" }, ""},
+		{"openai without model", func(c *Config) { c.Parser = "openai" }, "openai-model: model is empty"},
+		{"openai without base URL", func(c *Config) {
+			c.Parser, c.OpenAIModel, c.OpenAIBaseURL = "openai", "gpt-4o", "localhost"
+		}, "openai-base-url: invalid URL"},
+		{"other parser without OpenAI model", func(c *Config) { c.Parser = "fake" }, ""},
+		{"no workers", func(c *Config) { c.AnalyzeWorkers = 0 }, "analyze-workers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultConfig()
+			c.DataDir = t.TempDir()
+			tt.change(&c)
+
+			err := c.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
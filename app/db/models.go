@@ -8,26 +8,111 @@ import (
 	"database/sql"
 )
 
+type Analysis struct {
+	ID           int64    `db:"id" json:"id"`
+	ImportID     int64    `db:"import_id" json:"import_id"`
+	PromptHash   string   `db:"prompt_hash" json:"prompt_hash"`
+	Model        string   `db:"model" json:"model"`
+	Response     string   `db:"response" json:"response"`
+	InputTokens  int64    `db:"input_tokens" json:"input_tokens"`
+	OutputTokens int64    `db:"output_tokens" json:"output_tokens"`
+	LatencyMs    int64    `db:"latency_ms" json:"latency_ms"`
+	Outcome      string   `db:"outcome" json:"outcome"`
+	Error        string   `db:"error" json:"error"`
+	CreatedAt    UnixTime `db:"created_at" json:"created_at"`
+}
+
+type AuditLog struct {
+	ID         int64         `db:"id" json:"id"`
+	Actor      string        `db:"actor" json:"actor"`
+	Action     string        `db:"action" json:"action"`
+	ImportID   int64         `db:"import_id" json:"import_id"`
+	DeliveryID sql.NullInt64 `db:"delivery_id" json:"delivery_id"`
+	Before     string        `db:"before" json:"before"`
+	After      string        `db:"after" json:"after"`
+	CreatedAt  UnixTime      `db:"created_at" json:"created_at"`
+}
+
 type DeliveriesFt struct {
 	ID           string `db:"id" json:"id"`
 	LocationName string `db:"location_name" json:"location_name"`
 }
 
 type Delivery struct {
+	ID                  int64         `db:"id" json:"id"`
+	Date                UnixTime      `db:"date" json:"date"`
+	Schedule            string        `db:"schedule" json:"schedule"`
+	LocationType        string        `db:"location_type" json:"location_type"`
+	LocationName        string        `db:"location_name" json:"location_name"`
+	CreatedAt           UnixTime      `db:"created_at" json:"created_at"`
+	Notes               string        `db:"notes" json:"notes"`
+	ImportID            sql.NullInt64 `db:"import_id" json:"import_id"`
+	LocationID          sql.NullInt64 `db:"location_id" json:"location_id"`
+	LocationBase        string        `db:"location_base" json:"location_base"`
+	LocationSectors     string        `db:"location_sectors" json:"location_sectors"`
+	LocationSection     string        `db:"location_section" json:"location_section"`
+	LocationOrientation string        `db:"location_orientation" json:"location_orientation"`
+	ScheduleKind        string        `db:"schedule_kind" json:"schedule_kind"`
+	ScheduleStart       sql.NullInt64 `db:"schedule_start" json:"schedule_start"`
+	ScheduleEnd         sql.NullInt64 `db:"schedule_end" json:"schedule_end"`
+	ApprovedAt          *UnixTime     `db:"approved_at" json:"approved_at"`
+	Revision            int64         `db:"revision" json:"revision"`
+	UpdatedAt           *UnixTime     `db:"updated_at" json:"updated_at"`
+}
+
+type Import struct {
+	ID            int64         `db:"id" json:"id"`
+	FilePath      string        `db:"file_path" json:"file_path"`
+	FileHash      int64         `db:"file_hash" json:"file_hash"`
+	CreatedAt     UnixTime      `db:"created_at" json:"created_at"`
+	CompletedAt   *UnixTime     `db:"completed_at" json:"completed_at"`
+	FailedAt      *UnixTime     `db:"failed_at" json:"failed_at"`
+	Runs          sql.NullInt64 `db:"runs" json:"runs"`
+	Source        string        `db:"source" json:"source"`
+	PostID        string        `db:"post_id" json:"post_id"`
+	PostUrl       string        `db:"post_url" json:"post_url"`
+	PostText      string        `db:"post_text" json:"post_text"`
+	PostedAt      *UnixTime     `db:"posted_at" json:"posted_at"`
+	NextAttemptAt *UnixTime     `db:"next_attempt_at" json:"next_attempt_at"`
+	LastError     string        `db:"last_error" json:"last_error"`
+	DeadAt        *UnixTime     `db:"dead_at" json:"dead_at"`
+}
+
+type Location struct {
 	ID           int64    `db:"id" json:"id"`
-	Date         UnixTime `db:"date" json:"date"`
-	Schedule     string   `db:"schedule" json:"schedule"`
+	Slug         string   `db:"slug" json:"slug"`
+	Name         string   `db:"name" json:"name"`
 	LocationType string   `db:"location_type" json:"location_type"`
-	LocationName string   `db:"location_name" json:"location_name"`
 	CreatedAt    UnixTime `db:"created_at" json:"created_at"`
 }
 
-type Import struct {
-	ID          int64         `db:"id" json:"id"`
-	FilePath    string        `db:"file_path" json:"file_path"`
-	FileHash    int64         `db:"file_hash" json:"file_hash"`
-	CreatedAt   UnixTime      `db:"created_at" json:"created_at"`
-	CompletedAt *UnixTime     `db:"completed_at" json:"completed_at"`
-	FailedAt    *UnixTime     `db:"failed_at" json:"failed_at"`
-	Runs        sql.NullInt64 `db:"runs" json:"runs"`
+type LocationAlias struct {
+	ID         int64    `db:"id" json:"id"`
+	LocationID int64    `db:"location_id" json:"location_id"`
+	Alias      string   `db:"alias" json:"alias"`
+	AliasKey   string   `db:"alias_key" json:"alias_key"`
+	CreatedAt  UnixTime `db:"created_at" json:"created_at"`
+}
+
+type LocationReview struct {
+	ID           int64         `db:"id" json:"id"`
+	Name         string        `db:"name" json:"name"`
+	NameKey      string        `db:"name_key" json:"name_key"`
+	LocationType string        `db:"location_type" json:"location_type"`
+	Occurrences  int64         `db:"occurrences" json:"occurrences"`
+	ImportID     sql.NullInt64 `db:"import_id" json:"import_id"`
+	CreatedAt    UnixTime      `db:"created_at" json:"created_at"`
+	UpdatedAt    UnixTime      `db:"updated_at" json:"updated_at"`
+}
+
+type Quarantine struct {
+	ID           int64    `db:"id" json:"id"`
+	ImportID     int64    `db:"import_id" json:"import_id"`
+	Date         string   `db:"date" json:"date"`
+	Schedule     string   `db:"schedule" json:"schedule"`
+	LocationType string   `db:"location_type" json:"location_type"`
+	LocationName string   `db:"location_name" json:"location_name"`
+	Notes        string   `db:"notes" json:"notes"`
+	Reason       string   `db:"reason" json:"reason"`
+	CreatedAt    UnixTime `db:"created_at" json:"created_at"`
 }
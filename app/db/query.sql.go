@@ -10,9 +10,45 @@ import (
 	"database/sql"
 )
 
+const approveDelivery = `-- name: ApproveDelivery :one
+UPDATE deliveries
+SET approved_at = unixepoch()
+WHERE id = ?
+RETURNING id, date, schedule, location_type, location_name, created_at, notes, import_id, location_id, location_base, location_sectors, location_section, location_orientation, schedule_kind, schedule_start, schedule_end, approved_at, revision, updated_at
+`
+
+func (q *Queries) ApproveDelivery(ctx context.Context, id int64) (Delivery, error) {
+	row := q.db.QueryRowContext(ctx, approveDelivery, id)
+	var i Delivery
+	err := row.Scan(
+		&i.ID,
+		&i.Date,
+		&i.Schedule,
+		&i.LocationType,
+		&i.LocationName,
+		&i.CreatedAt,
+		&i.Notes,
+		&i.ImportID,
+		&i.LocationID,
+		&i.LocationBase,
+		&i.LocationSectors,
+		&i.LocationSection,
+		&i.LocationOrientation,
+		&i.ScheduleKind,
+		&i.ScheduleStart,
+		&i.ScheduleEnd,
+		&i.ApprovedAt,
+		&i.Revision,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const completeImport = `-- name: CompleteImport :exec
 UPDATE imports
 SET completed_at = unixepoch(),
+    next_attempt_at = NULL,
+    last_error = '',
     runs = runs + 1
 WHERE id = ?
 `
@@ -34,28 +70,131 @@ func (q *Queries) CountImportsByHash(ctx context.Context, fileHash int64) (int64
 	return count, err
 }
 
+const createAnalysis = `-- name: CreateAnalysis :one
+INSERT INTO analyses (
+  import_id, prompt_hash, model, response, input_tokens, output_tokens,
+  latency_ms, outcome, error, created_at
+) VALUES (
+  ?, ?, ?, ?, ?, ?, ?, ?, ?, unixepoch()
+)
+RETURNING id, import_id, prompt_hash, model, response, input_tokens, output_tokens, latency_ms, outcome, error, created_at
+`
+
+type CreateAnalysisParams struct {
+	ImportID     int64  `db:"import_id" json:"import_id"`
+	PromptHash   string `db:"prompt_hash" json:"prompt_hash"`
+	Model        string `db:"model" json:"model"`
+	Response     string `db:"response" json:"response"`
+	InputTokens  int64  `db:"input_tokens" json:"input_tokens"`
+	OutputTokens int64  `db:"output_tokens" json:"output_tokens"`
+	LatencyMs    int64  `db:"latency_ms" json:"latency_ms"`
+	Outcome      string `db:"outcome" json:"outcome"`
+	Error        string `db:"error" json:"error"`
+}
+
+func (q *Queries) CreateAnalysis(ctx context.Context, arg CreateAnalysisParams) (Analysis, error) {
+	row := q.db.QueryRowContext(ctx, createAnalysis,
+		arg.ImportID,
+		arg.PromptHash,
+		arg.Model,
+		arg.Response,
+		arg.InputTokens,
+		arg.OutputTokens,
+		arg.LatencyMs,
+		arg.Outcome,
+		arg.Error,
+	)
+	var i Analysis
+	err := row.Scan(
+		&i.ID,
+		&i.ImportID,
+		&i.PromptHash,
+		&i.Model,
+		&i.Response,
+		&i.InputTokens,
+		&i.OutputTokens,
+		&i.LatencyMs,
+		&i.Outcome,
+		&i.Error,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createAuditEntry = `-- name: CreateAuditEntry :exec
+INSERT INTO audit_log (
+  actor, action, import_id, delivery_id, before, after, created_at
+) VALUES (
+  ?, ?, ?, ?, ?, ?, unixepoch()
+)
+`
+
+type CreateAuditEntryParams struct {
+	Actor      string        `db:"actor" json:"actor"`
+	Action     string        `db:"action" json:"action"`
+	ImportID   int64         `db:"import_id" json:"import_id"`
+	DeliveryID sql.NullInt64 `db:"delivery_id" json:"delivery_id"`
+	Before     string        `db:"before" json:"before"`
+	After      string        `db:"after" json:"after"`
+}
+
+func (q *Queries) CreateAuditEntry(ctx context.Context, arg CreateAuditEntryParams) error {
+	_, err := q.db.ExecContext(ctx, createAuditEntry,
+		arg.Actor,
+		arg.Action,
+		arg.ImportID,
+		arg.DeliveryID,
+		arg.Before,
+		arg.After,
+	)
+	return err
+}
+
 const createDelivery = `-- name: CreateDelivery :one
 INSERT INTO deliveries (
-  date, schedule, location_type, location_name, created_at
+  date, schedule, schedule_kind, schedule_start, schedule_end,
+  location_type, location_name, location_base, location_sectors,
+  location_section, location_orientation, notes, import_id, location_id,
+  created_at
 ) VALUES (
-  ?, ?, ?, ?, unixepoch()
+  ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, unixepoch()
 )
-RETURNING id, date, schedule, location_type, location_name, created_at
+RETURNING id, date, schedule, location_type, location_name, created_at, notes, import_id, location_id, location_base, location_sectors, location_section, location_orientation, schedule_kind, schedule_start, schedule_end, approved_at, revision, updated_at
 `
 
 type CreateDeliveryParams struct {
-	Date         UnixTime `db:"date" json:"date"`
-	Schedule     string   `db:"schedule" json:"schedule"`
-	LocationType string   `db:"location_type" json:"location_type"`
-	LocationName string   `db:"location_name" json:"location_name"`
+	Date                UnixTime      `db:"date" json:"date"`
+	Schedule            string        `db:"schedule" json:"schedule"`
+	ScheduleKind        string        `db:"schedule_kind" json:"schedule_kind"`
+	ScheduleStart       sql.NullInt64 `db:"schedule_start" json:"schedule_start"`
+	ScheduleEnd         sql.NullInt64 `db:"schedule_end" json:"schedule_end"`
+	LocationType        string        `db:"location_type" json:"location_type"`
+	LocationName        string        `db:"location_name" json:"location_name"`
+	LocationBase        string        `db:"location_base" json:"location_base"`
+	LocationSectors     string        `db:"location_sectors" json:"location_sectors"`
+	LocationSection     string        `db:"location_section" json:"location_section"`
+	LocationOrientation string        `db:"location_orientation" json:"location_orientation"`
+	Notes               string        `db:"notes" json:"notes"`
+	ImportID            sql.NullInt64 `db:"import_id" json:"import_id"`
+	LocationID          sql.NullInt64 `db:"location_id" json:"location_id"`
 }
 
 func (q *Queries) CreateDelivery(ctx context.Context, arg CreateDeliveryParams) (Delivery, error) {
 	row := q.db.QueryRowContext(ctx, createDelivery,
 		arg.Date,
 		arg.Schedule,
+		arg.ScheduleKind,
+		arg.ScheduleStart,
+		arg.ScheduleEnd,
 		arg.LocationType,
 		arg.LocationName,
+		arg.LocationBase,
+		arg.LocationSectors,
+		arg.LocationSection,
+		arg.LocationOrientation,
+		arg.Notes,
+		arg.ImportID,
+		arg.LocationID,
 	)
 	var i Delivery
 	err := row.Scan(
@@ -65,26 +204,53 @@ func (q *Queries) CreateDelivery(ctx context.Context, arg CreateDeliveryParams)
 		&i.LocationType,
 		&i.LocationName,
 		&i.CreatedAt,
+		&i.Notes,
+		&i.ImportID,
+		&i.LocationID,
+		&i.LocationBase,
+		&i.LocationSectors,
+		&i.LocationSection,
+		&i.LocationOrientation,
+		&i.ScheduleKind,
+		&i.ScheduleStart,
+		&i.ScheduleEnd,
+		&i.ApprovedAt,
+		&i.Revision,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
 
 const createImport = `-- name: CreateImport :one
 INSERT INTO imports (
-  file_path, file_hash, completed_at, runs, created_at
+  file_path, file_hash, source, post_id, post_url, post_text, posted_at,
+  completed_at, runs, created_at
 ) VALUES (
-  ?, ?, NULL, 0, unixepoch()
+  ?, ?, ?, ?, ?, ?, ?, NULL, 0, unixepoch()
 )
-RETURNING id, file_path, file_hash, created_at, completed_at, failed_at, runs
+RETURNING id, file_path, file_hash, created_at, completed_at, failed_at, runs, source, post_id, post_url, post_text, posted_at, next_attempt_at, last_error, dead_at
 `
 
 type CreateImportParams struct {
-	FilePath string `db:"file_path" json:"file_path"`
-	FileHash int64  `db:"file_hash" json:"file_hash"`
+	FilePath string    `db:"file_path" json:"file_path"`
+	FileHash int64     `db:"file_hash" json:"file_hash"`
+	Source   string    `db:"source" json:"source"`
+	PostID   string    `db:"post_id" json:"post_id"`
+	PostUrl  string    `db:"post_url" json:"post_url"`
+	PostText string    `db:"post_text" json:"post_text"`
+	PostedAt *UnixTime `db:"posted_at" json:"posted_at"`
 }
 
 func (q *Queries) CreateImport(ctx context.Context, arg CreateImportParams) (Import, error) {
-	row := q.db.QueryRowContext(ctx, createImport, arg.FilePath, arg.FileHash)
+	row := q.db.QueryRowContext(ctx, createImport,
+		arg.FilePath,
+		arg.FileHash,
+		arg.Source,
+		arg.PostID,
+		arg.PostUrl,
+		arg.PostText,
+		arg.PostedAt,
+	)
 	var i Import
 	err := row.Scan(
 		&i.ID,
@@ -94,6 +260,70 @@ func (q *Queries) CreateImport(ctx context.Context, arg CreateImportParams) (Imp
 		&i.CompletedAt,
 		&i.FailedAt,
 		&i.Runs,
+		&i.Source,
+		&i.PostID,
+		&i.PostUrl,
+		&i.PostText,
+		&i.PostedAt,
+		&i.NextAttemptAt,
+		&i.LastError,
+		&i.DeadAt,
+	)
+	return i, err
+}
+
+const createLocation = `-- name: CreateLocation :one
+INSERT INTO locations (
+  slug, name, location_type, created_at
+) VALUES (
+  ?, ?, ?, unixepoch()
+)
+RETURNING id, slug, name, location_type, created_at
+`
+
+type CreateLocationParams struct {
+	Slug         string `db:"slug" json:"slug"`
+	Name         string `db:"name" json:"name"`
+	LocationType string `db:"location_type" json:"location_type"`
+}
+
+func (q *Queries) CreateLocation(ctx context.Context, arg CreateLocationParams) (Location, error) {
+	row := q.db.QueryRowContext(ctx, createLocation, arg.Slug, arg.Name, arg.LocationType)
+	var i Location
+	err := row.Scan(
+		&i.ID,
+		&i.Slug,
+		&i.Name,
+		&i.LocationType,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createLocationAlias = `-- name: CreateLocationAlias :one
+INSERT INTO location_aliases (
+  location_id, alias, alias_key, created_at
+) VALUES (
+  ?, ?, ?, unixepoch()
+)
+RETURNING id, location_id, alias, alias_key, created_at
+`
+
+type CreateLocationAliasParams struct {
+	LocationID int64  `db:"location_id" json:"location_id"`
+	Alias      string `db:"alias" json:"alias"`
+	AliasKey   string `db:"alias_key" json:"alias_key"`
+}
+
+func (q *Queries) CreateLocationAlias(ctx context.Context, arg CreateLocationAliasParams) (LocationAlias, error) {
+	row := q.db.QueryRowContext(ctx, createLocationAlias, arg.LocationID, arg.Alias, arg.AliasKey)
+	var i LocationAlias
+	err := row.Scan(
+		&i.ID,
+		&i.LocationID,
+		&i.Alias,
+		&i.AliasKey,
+		&i.CreatedAt,
 	)
 	return i, err
 }
@@ -108,20 +338,217 @@ func (q *Queries) DeleteDelivery(ctx context.Context, id int64) error {
 	return err
 }
 
+const deleteLocationReview = `-- name: DeleteLocationReview :exec
+DELETE FROM location_reviews
+WHERE name_key = ?
+`
+
+func (q *Queries) DeleteLocationReview(ctx context.Context, nameKey string) error {
+	_, err := q.db.ExecContext(ctx, deleteLocationReview, nameKey)
+	return err
+}
+
+const deleteQuarantineByImport = `-- name: DeleteQuarantineByImport :exec
+DELETE FROM quarantine
+WHERE import_id = ?
+`
+
+func (q *Queries) DeleteQuarantineByImport(ctx context.Context, importID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteQuarantineByImport, importID)
+	return err
+}
+
+const deleteQuarantinedDelivery = `-- name: DeleteQuarantinedDelivery :exec
+DELETE FROM quarantine
+WHERE id = ?
+`
+
+func (q *Queries) DeleteQuarantinedDelivery(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, deleteQuarantinedDelivery, id)
+	return err
+}
+
 const failImport = `-- name: FailImport :exec
 UPDATE imports
 SET failed_at = unixepoch(),
+    last_error = ?,
+    next_attempt_at = ?,
+    dead_at = ?,
     runs = runs + 1
 WHERE id = ?
 `
 
-func (q *Queries) FailImport(ctx context.Context, id int64) error {
-	_, err := q.db.ExecContext(ctx, failImport, id)
+type FailImportParams struct {
+	LastError     string    `db:"last_error" json:"last_error"`
+	NextAttemptAt *UnixTime `db:"next_attempt_at" json:"next_attempt_at"`
+	DeadAt        *UnixTime `db:"dead_at" json:"dead_at"`
+	ID            int64     `db:"id" json:"id"`
+}
+
+func (q *Queries) FailImport(ctx context.Context, arg FailImportParams) error {
+	_, err := q.db.ExecContext(ctx, failImport,
+		arg.LastError,
+		arg.NextAttemptAt,
+		arg.DeadAt,
+		arg.ID,
+	)
 	return err
 }
 
+const filterDeliveries = `-- name: FilterDeliveries :many
+SELECT id, date, schedule, location_type, location_name, created_at, notes, import_id, location_id, location_base, location_sectors, location_section, location_orientation, schedule_kind, schedule_start, schedule_end, approved_at, revision, updated_at FROM deliveries
+WHERE date >= ?
+  AND date <= ?
+  AND location_type LIKE CAST(? AS TEXT)
+  AND schedule_kind LIKE CAST(? AS TEXT)
+ORDER BY date DESC, id DESC
+LIMIT ? OFFSET ?
+`
+
+type FilterDeliveriesParams struct {
+	FromDate     UnixTime `db:"from_date" json:"from_date"`
+	ToDate       UnixTime `db:"to_date" json:"to_date"`
+	LocationType string   `db:"location_type" json:"location_type"`
+	ScheduleKind string   `db:"schedule_kind" json:"schedule_kind"`
+	Limit        int64    `db:"limit" json:"limit"`
+	Offset       int64    `db:"offset" json:"offset"`
+}
+
+func (q *Queries) FilterDeliveries(ctx context.Context, arg FilterDeliveriesParams) ([]Delivery, error) {
+	rows, err := q.db.QueryContext(ctx, filterDeliveries,
+		arg.FromDate,
+		arg.ToDate,
+		arg.LocationType,
+		arg.ScheduleKind,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Delivery
+	for rows.Next() {
+		var i Delivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.Date,
+			&i.Schedule,
+			&i.LocationType,
+			&i.LocationName,
+			&i.CreatedAt,
+			&i.Notes,
+			&i.ImportID,
+			&i.LocationID,
+			&i.LocationBase,
+			&i.LocationSectors,
+			&i.LocationSection,
+			&i.LocationOrientation,
+			&i.ScheduleKind,
+			&i.ScheduleStart,
+			&i.ScheduleEnd,
+			&i.ApprovedAt,
+			&i.Revision,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const filterDeliveriesByName = `-- name: FilterDeliveriesByName :many
+SELECT d.id, d.date, d.schedule, d.location_type, d.location_name, d.created_at, d.notes, d.import_id, d.location_id, d.location_base, d.location_sectors, d.location_section, d.location_orientation, d.schedule_kind, d.schedule_start, d.schedule_end, d.approved_at, d.revision, d.updated_at
+FROM deliveries d
+JOIN deliveries_fts fts ON d.id = fts.id
+WHERE d.date >= ?
+  AND d.date <= ?
+  AND d.location_type LIKE CAST(? AS TEXT)
+  AND d.schedule_kind LIKE CAST(? AS TEXT)
+  AND fts.location_name MATCH ?
+  AND (d.location_sectors = ''
+    OR ',' || d.location_sectors || ',' LIKE CAST(? AS TEXT))
+  AND (d.location_section = ''
+    OR d.location_section LIKE CAST(? AS TEXT))
+GROUP BY d.id
+ORDER BY d.date DESC, d.id DESC
+LIMIT ? OFFSET ?
+`
+
+type FilterDeliveriesByNameParams struct {
+	FromDate     UnixTime `db:"from_date" json:"from_date"`
+	ToDate       UnixTime `db:"to_date" json:"to_date"`
+	LocationType string   `db:"location_type" json:"location_type"`
+	ScheduleKind string   `db:"schedule_kind" json:"schedule_kind"`
+	LocationName string   `db:"location_name" json:"location_name"`
+	Sectors      string   `db:"sectors" json:"sectors"`
+	Section      string   `db:"section" json:"section"`
+	Limit        int64    `db:"limit" json:"limit"`
+	Offset       int64    `db:"offset" json:"offset"`
+}
+
+func (q *Queries) FilterDeliveriesByName(ctx context.Context, arg FilterDeliveriesByNameParams) ([]Delivery, error) {
+	rows, err := q.db.QueryContext(ctx, filterDeliveriesByName,
+		arg.FromDate,
+		arg.ToDate,
+		arg.LocationType,
+		arg.ScheduleKind,
+		arg.LocationName,
+		arg.Sectors,
+		arg.Section,
+		arg.Limit,
+		arg.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Delivery
+	for rows.Next() {
+		var i Delivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.Date,
+			&i.Schedule,
+			&i.LocationType,
+			&i.LocationName,
+			&i.CreatedAt,
+			&i.Notes,
+			&i.ImportID,
+			&i.LocationID,
+			&i.LocationBase,
+			&i.LocationSectors,
+			&i.LocationSection,
+			&i.LocationOrientation,
+			&i.ScheduleKind,
+			&i.ScheduleStart,
+			&i.ScheduleEnd,
+			&i.ApprovedAt,
+			&i.Revision,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getDelivery = `-- name: GetDelivery :one
-SELECT id, date, schedule, location_type, location_name, created_at FROM deliveries
+SELECT id, date, schedule, location_type, location_name, created_at, notes, import_id, location_id, location_base, location_sectors, location_section, location_orientation, schedule_kind, schedule_start, schedule_end, approved_at, revision, updated_at FROM deliveries
 WHERE id = ? LIMIT 1
 `
 
@@ -135,12 +562,53 @@ func (q *Queries) GetDelivery(ctx context.Context, id int64) (Delivery, error) {
 		&i.LocationType,
 		&i.LocationName,
 		&i.CreatedAt,
+		&i.Notes,
+		&i.ImportID,
+		&i.LocationID,
+		&i.LocationBase,
+		&i.LocationSectors,
+		&i.LocationSection,
+		&i.LocationOrientation,
+		&i.ScheduleKind,
+		&i.ScheduleStart,
+		&i.ScheduleEnd,
+		&i.ApprovedAt,
+		&i.Revision,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getImport = `-- name: GetImport :one
+SELECT id, file_path, file_hash, created_at, completed_at, failed_at, runs, source, post_id, post_url, post_text, posted_at, next_attempt_at, last_error, dead_at FROM imports
+WHERE id = ? LIMIT 1
+`
+
+func (q *Queries) GetImport(ctx context.Context, id int64) (Import, error) {
+	row := q.db.QueryRowContext(ctx, getImport, id)
+	var i Import
+	err := row.Scan(
+		&i.ID,
+		&i.FilePath,
+		&i.FileHash,
+		&i.CreatedAt,
+		&i.CompletedAt,
+		&i.FailedAt,
+		&i.Runs,
+		&i.Source,
+		&i.PostID,
+		&i.PostUrl,
+		&i.PostText,
+		&i.PostedAt,
+		&i.NextAttemptAt,
+		&i.LastError,
+		&i.DeadAt,
 	)
 	return i, err
 }
 
 const getLatestImport = `-- name: GetLatestImport :one
-SELECT id, file_path, file_hash, created_at, completed_at, failed_at, runs FROM imports
+SELECT id, file_path, file_hash, created_at, completed_at, failed_at, runs, source, post_id, post_url, post_text, posted_at, next_attempt_at, last_error, dead_at FROM imports
 WHERE completed_at IS NOT NULL
 ORDER BY created_at DESC
 LIMIT 1
@@ -157,19 +625,46 @@ func (q *Queries) GetLatestImport(ctx context.Context) (Import, error) {
 		&i.CompletedAt,
 		&i.FailedAt,
 		&i.Runs,
+		&i.Source,
+		&i.PostID,
+		&i.PostUrl,
+		&i.PostText,
+		&i.PostedAt,
+		&i.NextAttemptAt,
+		&i.LastError,
+		&i.DeadAt,
+	)
+	return i, err
+}
+
+const getLocationBySlug = `-- name: GetLocationBySlug :one
+SELECT id, slug, name, location_type, created_at FROM locations
+WHERE slug = ? LIMIT 1
+`
+
+func (q *Queries) GetLocationBySlug(ctx context.Context, slug string) (Location, error) {
+	row := q.db.QueryRowContext(ctx, getLocationBySlug, slug)
+	var i Location
+	err := row.Scan(
+		&i.ID,
+		&i.Slug,
+		&i.Name,
+		&i.LocationType,
+		&i.CreatedAt,
 	)
 	return i, err
 }
 
 const getPendingImports = `-- name: GetPendingImports :many
-SELECT id, file_path, file_hash, created_at, completed_at, failed_at, runs FROM imports
+SELECT id, file_path, file_hash, created_at, completed_at, failed_at, runs, source, post_id, post_url, post_text, posted_at, next_attempt_at, last_error, dead_at FROM imports
 WHERE completed_at IS NULL
-AND runs < ?
+AND dead_at IS NULL
+AND (next_attempt_at IS NULL OR next_attempt_at <= unixepoch())
 ORDER BY created_at DESC
 `
 
-func (q *Queries) GetPendingImports(ctx context.Context, runs sql.NullInt64) ([]Import, error) {
-	rows, err := q.db.QueryContext(ctx, getPendingImports, runs)
+func (q *Queries) GetPendingImports(ctx context.Context) ([]Import, error) {
+	rows, err := q.db.QueryContext(ctx, getPendingImports)
 	if err != nil {
 		return nil, err
 	}
@@ -185,6 +680,14 @@ func (q *Queries) GetPendingImports(ctx context.Context, runs sql.NullInt64) ([]
 			&i.CompletedAt,
 			&i.FailedAt,
 			&i.Runs,
+			&i.Source,
+			&i.PostID,
+			&i.PostUrl,
+			&i.PostText,
+			&i.PostedAt,
+			&i.NextAttemptAt,
+			&i.LastError,
+			&i.DeadAt,
 		); err != nil {
 			return nil, err
 		}
@@ -199,14 +702,35 @@ func (q *Queries) GetPendingImports(ctx context.Context, runs sql.NullInt64) ([]
 	return items, nil
 }
 
-const listDeliveries = `-- name: ListDeliveries :many
-SELECT id, date, schedule, location_type, location_name, created_at FROM deliveries
-WHERE "date" > ?
-ORDER BY "date" DESC
+const getQuarantinedDelivery = `-- name: GetQuarantinedDelivery :one
+SELECT id, import_id, date, schedule, location_type, location_name, notes, reason, created_at FROM quarantine
+WHERE id = ? LIMIT 1
 `
 
-func (q *Queries) ListDeliveries(ctx context.Context, date UnixTime) ([]Delivery, error) {
-	rows, err := q.db.QueryContext(ctx, listDeliveries, date)
+func (q *Queries) GetQuarantinedDelivery(ctx context.Context, id int64) (Quarantine, error) {
+	row := q.db.QueryRowContext(ctx, getQuarantinedDelivery, id)
+	var i Quarantine
+	err := row.Scan(
+		&i.ID,
+		&i.ImportID,
+		&i.Date,
+		&i.Schedule,
+		&i.LocationType,
+		&i.LocationName,
+		&i.Notes,
+		&i.Reason,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listAllDeliveries = `-- name: ListAllDeliveries :many
+SELECT id, date, schedule, location_type, location_name, created_at, notes, import_id, location_id, location_base, location_sectors, location_section, location_orientation, schedule_kind, schedule_start, schedule_end, approved_at, revision, updated_at FROM deliveries
+ORDER BY id
+`
+
+func (q *Queries) ListAllDeliveries(ctx context.Context) ([]Delivery, error) {
+	rows, err := q.db.QueryContext(ctx, listAllDeliveries)
 	if err != nil {
 		return nil, err
 	}
@@ -221,6 +745,19 @@ func (q *Queries) ListDeliveries(ctx context.Context, date UnixTime) ([]Delivery
 			&i.LocationType,
 			&i.LocationName,
 			&i.CreatedAt,
+			&i.Notes,
+			&i.ImportID,
+			&i.LocationID,
+			&i.LocationBase,
+			&i.LocationSectors,
+			&i.LocationSection,
+			&i.LocationOrientation,
+			&i.ScheduleKind,
+			&i.ScheduleStart,
+			&i.ScheduleEnd,
+			&i.ApprovedAt,
+			&i.Revision,
+			&i.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -235,36 +772,70 @@ func (q *Queries) ListDeliveries(ctx context.Context, date UnixTime) ([]Delivery
 	return items, nil
 }
 
-const searchDeliveriesByName = `-- name: SearchDeliveriesByName :many
-SELECT d.id, d.date, d.schedule, d.location_type, d.location_name, d.created_at
-FROM deliveries d
-JOIN deliveries_fts fts ON d.id = fts.id
-WHERE d.date > ?
-  AND fts.location_name MATCH ?
-GROUP BY d.id
-ORDER BY d.date DESC
+const listAnalysesByImport = `-- name: ListAnalysesByImport :many
+SELECT id, import_id, prompt_hash, model, response, input_tokens, output_tokens, latency_ms, outcome, error, created_at FROM analyses
+WHERE import_id = ?
+ORDER BY created_at DESC, id DESC
 `
 
-type SearchDeliveriesByNameParams struct {
-	Date         UnixTime `db:"date" json:"date"`
-	LocationName string   `db:"location_name" json:"location_name"`
+func (q *Queries) ListAnalysesByImport(ctx context.Context, importID int64) ([]Analysis, error) {
+	rows, err := q.db.QueryContext(ctx, listAnalysesByImport, importID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Analysis
+	for rows.Next() {
+		var i Analysis
+		if err := rows.Scan(
+			&i.ID,
+			&i.ImportID,
+			&i.PromptHash,
+			&i.Model,
+			&i.Response,
+			&i.InputTokens,
+			&i.OutputTokens,
+			&i.LatencyMs,
+			&i.Outcome,
+			&i.Error,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-func (q *Queries) SearchDeliveriesByName(ctx context.Context, arg SearchDeliveriesByNameParams) ([]Delivery, error) {
-	rows, err := q.db.QueryContext(ctx, searchDeliveriesByName, arg.Date, arg.LocationName)
+const listAuditLogByImport = `-- name: ListAuditLogByImport :many
+SELECT id, actor, action, import_id, delivery_id, before, after, created_at FROM audit_log
+WHERE import_id = ?
+ORDER BY id DESC
+`
+
+func (q *Queries) ListAuditLogByImport(ctx context.Context, importID int64) ([]AuditLog, error) {
+	rows, err := q.db.QueryContext(ctx, listAuditLogByImport, importID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Delivery
+	var items []AuditLog
 	for rows.Next() {
-		var i Delivery
+		var i AuditLog
 		if err := rows.Scan(
 			&i.ID,
-			&i.Date,
-			&i.Schedule,
-			&i.LocationType,
-			&i.LocationName,
+			&i.Actor,
+			&i.Action,
+			&i.ImportID,
+			&i.DeliveryID,
+			&i.Before,
+			&i.After,
 			&i.CreatedAt,
 		); err != nil {
 			return nil, err
@@ -279,3 +850,885 @@ func (q *Queries) SearchDeliveriesByName(ctx context.Context, arg SearchDeliveri
 	}
 	return items, nil
 }
+
+const listDeadImports = `-- name: ListDeadImports :many
+SELECT id, file_path, file_hash, created_at, completed_at, failed_at, runs, source, post_id, post_url, post_text, posted_at, next_attempt_at, last_error, dead_at FROM imports
+WHERE dead_at IS NOT NULL
+ORDER BY dead_at DESC, id DESC
+`
+
+func (q *Queries) ListDeadImports(ctx context.Context) ([]Import, error) {
+	rows, err := q.db.QueryContext(ctx, listDeadImports)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Import
+	for rows.Next() {
+		var i Import
+		if err := rows.Scan(
+			&i.ID,
+			&i.FilePath,
+			&i.FileHash,
+			&i.CreatedAt,
+			&i.CompletedAt,
+			&i.FailedAt,
+			&i.Runs,
+			&i.Source,
+			&i.PostID,
+			&i.PostUrl,
+			&i.PostText,
+			&i.PostedAt,
+			&i.NextAttemptAt,
+			&i.LastError,
+			&i.DeadAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDeliveries = `-- name: ListDeliveries :many
+SELECT id, date, schedule, location_type, location_name, created_at, notes, import_id, location_id, location_base, location_sectors, location_section, location_orientation, schedule_kind, schedule_start, schedule_end, approved_at, revision, updated_at FROM deliveries
+WHERE "date" > ?
+ORDER BY "date" DESC
+`
+
+func (q *Queries) ListDeliveries(ctx context.Context, date UnixTime) ([]Delivery, error) {
+	rows, err := q.db.QueryContext(ctx, listDeliveries, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Delivery
+	for rows.Next() {
+		var i Delivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.Date,
+			&i.Schedule,
+			&i.LocationType,
+			&i.LocationName,
+			&i.CreatedAt,
+			&i.Notes,
+			&i.ImportID,
+			&i.LocationID,
+			&i.LocationBase,
+			&i.LocationSectors,
+			&i.LocationSection,
+			&i.LocationOrientation,
+			&i.ScheduleKind,
+			&i.ScheduleStart,
+			&i.ScheduleEnd,
+			&i.ApprovedAt,
+			&i.Revision,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDeliveriesByImport = `-- name: ListDeliveriesByImport :many
+SELECT id, date, schedule, location_type, location_name, created_at, notes, import_id, location_id, location_base, location_sectors, location_section, location_orientation, schedule_kind, schedule_start, schedule_end, approved_at, revision, updated_at FROM deliveries
+WHERE import_id = ?
+ORDER BY id
+`
+
+func (q *Queries) ListDeliveriesByImport(ctx context.Context, importID sql.NullInt64) ([]Delivery, error) {
+	rows, err := q.db.QueryContext(ctx, listDeliveriesByImport, importID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Delivery
+	for rows.Next() {
+		var i Delivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.Date,
+			&i.Schedule,
+			&i.LocationType,
+			&i.LocationName,
+			&i.CreatedAt,
+			&i.Notes,
+			&i.ImportID,
+			&i.LocationID,
+			&i.LocationBase,
+			&i.LocationSectors,
+			&i.LocationSection,
+			&i.LocationOrientation,
+			&i.ScheduleKind,
+			&i.ScheduleStart,
+			&i.ScheduleEnd,
+			&i.ApprovedAt,
+			&i.Revision,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listDeliveriesByLocation = `-- name: ListDeliveriesByLocation :many
+SELECT id, date, schedule, location_type, location_name, created_at, notes, import_id, location_id, location_base, location_sectors, location_section, location_orientation, schedule_kind, schedule_start, schedule_end, approved_at, revision, updated_at FROM deliveries
+WHERE location_id = ?
+  AND "date" > ?
+ORDER BY "date" DESC, id DESC
+`
+
+type ListDeliveriesByLocationParams struct {
+	LocationID sql.NullInt64 `db:"location_id" json:"location_id"`
+	Date       UnixTime      `db:"date" json:"date"`
+}
+
+func (q *Queries) ListDeliveriesByLocation(ctx context.Context, arg ListDeliveriesByLocationParams) ([]Delivery, error) {
+	rows, err := q.db.QueryContext(ctx, listDeliveriesByLocation, arg.LocationID, arg.Date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Delivery
+	for rows.Next() {
+		var i Delivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.Date,
+			&i.Schedule,
+			&i.LocationType,
+			&i.LocationName,
+			&i.CreatedAt,
+			&i.Notes,
+			&i.ImportID,
+			&i.LocationID,
+			&i.LocationBase,
+			&i.LocationSectors,
+			&i.LocationSection,
+			&i.LocationOrientation,
+			&i.ScheduleKind,
+			&i.ScheduleStart,
+			&i.ScheduleEnd,
+			&i.ApprovedAt,
+			&i.Revision,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listFailedImports = `-- name: ListFailedImports :many
+SELECT id, file_path, file_hash, created_at, completed_at, failed_at, runs, source, post_id, post_url, post_text, posted_at, next_attempt_at, last_error, dead_at FROM imports
+WHERE completed_at IS NULL
+AND failed_at IS NOT NULL
+ORDER BY created_at DESC
+`
+
+func (q *Queries) ListFailedImports(ctx context.Context) ([]Import, error) {
+	rows, err := q.db.QueryContext(ctx, listFailedImports)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Import
+	for rows.Next() {
+		var i Import
+		if err := rows.Scan(
+			&i.ID,
+			&i.FilePath,
+			&i.FileHash,
+			&i.CreatedAt,
+			&i.CompletedAt,
+			&i.FailedAt,
+			&i.Runs,
+			&i.Source,
+			&i.PostID,
+			&i.PostUrl,
+			&i.PostText,
+			&i.PostedAt,
+			&i.NextAttemptAt,
+			&i.LastError,
+			&i.DeadAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listImports = `-- name: ListImports :many
+SELECT id, file_path, file_hash, created_at, completed_at, failed_at, runs, source, post_id, post_url, post_text, posted_at, next_attempt_at, last_error, dead_at FROM imports
+ORDER BY created_at DESC, id DESC
+LIMIT ?
+`
+
+func (q *Queries) ListImports(ctx context.Context, limit int64) ([]Import, error) {
+	rows, err := q.db.QueryContext(ctx, listImports, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Import
+	for rows.Next() {
+		var i Import
+		if err := rows.Scan(
+			&i.ID,
+			&i.FilePath,
+			&i.FileHash,
+			&i.CreatedAt,
+			&i.CompletedAt,
+			&i.FailedAt,
+			&i.Runs,
+			&i.Source,
+			&i.PostID,
+			&i.PostUrl,
+			&i.PostText,
+			&i.PostedAt,
+			&i.NextAttemptAt,
+			&i.LastError,
+			&i.DeadAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listImportsSince = `-- name: ListImportsSince :many
+SELECT id, file_path, file_hash, created_at, completed_at, failed_at, runs, source, post_id, post_url, post_text, posted_at, next_attempt_at, last_error, dead_at FROM imports
+WHERE COALESCE(posted_at, created_at) >= CAST(? AS TIMESTAMP)
+ORDER BY created_at DESC
+`
+
+func (q *Queries) ListImportsSince(ctx context.Context, since UnixTime) ([]Import, error) {
+	rows, err := q.db.QueryContext(ctx, listImportsSince, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Import
+	for rows.Next() {
+		var i Import
+		if err := rows.Scan(
+			&i.ID,
+			&i.FilePath,
+			&i.FileHash,
+			&i.CreatedAt,
+			&i.CompletedAt,
+			&i.FailedAt,
+			&i.Runs,
+			&i.Source,
+			&i.PostID,
+			&i.PostUrl,
+			&i.PostText,
+			&i.PostedAt,
+			&i.NextAttemptAt,
+			&i.LastError,
+			&i.DeadAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLocationAliases = `-- name: ListLocationAliases :many
+SELECT id, location_id, alias, alias_key, created_at FROM location_aliases
+ORDER BY id
+`
+
+func (q *Queries) ListLocationAliases(ctx context.Context) ([]LocationAlias, error) {
+	rows, err := q.db.QueryContext(ctx, listLocationAliases)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LocationAlias
+	for rows.Next() {
+		var i LocationAlias
+		if err := rows.Scan(
+			&i.ID,
+			&i.LocationID,
+			&i.Alias,
+			&i.AliasKey,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLocationReviews = `-- name: ListLocationReviews :many
+SELECT id, name, name_key, location_type, occurrences, import_id, created_at, updated_at FROM location_reviews
+ORDER BY occurrences DESC, name
+`
+
+func (q *Queries) ListLocationReviews(ctx context.Context) ([]LocationReview, error) {
+	rows, err := q.db.QueryContext(ctx, listLocationReviews)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LocationReview
+	for rows.Next() {
+		var i LocationReview
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.NameKey,
+			&i.LocationType,
+			&i.Occurrences,
+			&i.ImportID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLocations = `-- name: ListLocations :many
+SELECT id, slug, name, location_type, created_at FROM locations
+ORDER BY name
+`
+
+func (q *Queries) ListLocations(ctx context.Context) ([]Location, error) {
+	rows, err := q.db.QueryContext(ctx, listLocations)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Location
+	for rows.Next() {
+		var i Location
+		if err := rows.Scan(
+			&i.ID,
+			&i.Slug,
+			&i.Name,
+			&i.LocationType,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listQuarantine = `-- name: ListQuarantine :many
+SELECT id, import_id, date, schedule, location_type, location_name, notes, reason, created_at FROM quarantine
+ORDER BY id
+`
+
+func (q *Queries) ListQuarantine(ctx context.Context) ([]Quarantine, error) {
+	rows, err := q.db.QueryContext(ctx, listQuarantine)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Quarantine
+	for rows.Next() {
+		var i Quarantine
+		if err := rows.Scan(
+			&i.ID,
+			&i.ImportID,
+			&i.Date,
+			&i.Schedule,
+			&i.LocationType,
+			&i.LocationName,
+			&i.Notes,
+			&i.Reason,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listQuarantineByImport = `-- name: ListQuarantineByImport :many
+SELECT id, import_id, date, schedule, location_type, location_name, notes, reason, created_at FROM quarantine
+WHERE import_id = ?
+ORDER BY id
+`
+
+func (q *Queries) ListQuarantineByImport(ctx context.Context, importID int64) ([]Quarantine, error) {
+	rows, err := q.db.QueryContext(ctx, listQuarantineByImport, importID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Quarantine
+	for rows.Next() {
+		var i Quarantine
+		if err := rows.Scan(
+			&i.ID,
+			&i.ImportID,
+			&i.Date,
+			&i.Schedule,
+			&i.LocationType,
+			&i.LocationName,
+			&i.Notes,
+			&i.Reason,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnlinkedDeliveries = `-- name: ListUnlinkedDeliveries :many
+SELECT id, date, schedule, location_type, location_name, created_at, notes, import_id, location_id, location_base, location_sectors, location_section, location_orientation, schedule_kind, schedule_start, schedule_end, approved_at, revision, updated_at FROM deliveries
+WHERE location_id IS NULL
+ORDER BY id
+`
+
+func (q *Queries) ListUnlinkedDeliveries(ctx context.Context) ([]Delivery, error) {
+	rows, err := q.db.QueryContext(ctx, listUnlinkedDeliveries)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Delivery
+	for rows.Next() {
+		var i Delivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.Date,
+			&i.Schedule,
+			&i.LocationType,
+			&i.LocationName,
+			&i.CreatedAt,
+			&i.Notes,
+			&i.ImportID,
+			&i.LocationID,
+			&i.LocationBase,
+			&i.LocationSectors,
+			&i.LocationSection,
+			&i.LocationOrientation,
+			&i.ScheduleKind,
+			&i.ScheduleStart,
+			&i.ScheduleEnd,
+			&i.ApprovedAt,
+			&i.Revision,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const quarantineDelivery = `-- name: QuarantineDelivery :one
+INSERT INTO quarantine (
+  import_id, date, schedule, location_type, location_name, notes, reason,
+  created_at
+) VALUES (
+  ?, ?, ?, ?, ?, ?, ?, unixepoch()
+)
+RETURNING id, import_id, date, schedule, location_type, location_name, notes, reason, created_at
+`
+
+type QuarantineDeliveryParams struct {
+	ImportID     int64  `db:"import_id" json:"import_id"`
+	Date         string `db:"date" json:"date"`
+	Schedule     string `db:"schedule" json:"schedule"`
+	LocationType string `db:"location_type" json:"location_type"`
+	LocationName string `db:"location_name" json:"location_name"`
+	Notes        string `db:"notes" json:"notes"`
+	Reason       string `db:"reason" json:"reason"`
+}
+
+func (q *Queries) QuarantineDelivery(ctx context.Context, arg QuarantineDeliveryParams) (Quarantine, error) {
+	row := q.db.QueryRowContext(ctx, quarantineDelivery,
+		arg.ImportID,
+		arg.Date,
+		arg.Schedule,
+		arg.LocationType,
+		arg.LocationName,
+		arg.Notes,
+		arg.Reason,
+	)
+	var i Quarantine
+	err := row.Scan(
+		&i.ID,
+		&i.ImportID,
+		&i.Date,
+		&i.Schedule,
+		&i.LocationType,
+		&i.LocationName,
+		&i.Notes,
+		&i.Reason,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const queueLocationReview = `-- name: QueueLocationReview :exec
+INSERT INTO location_reviews (
+  name, name_key, location_type, occurrences, import_id, created_at, updated_at
+) VALUES (
+  ?, ?, ?, 1, ?, unixepoch(), unixepoch()
+)
+ON CONFLICT (name_key) DO UPDATE
+SET occurrences = occurrences + 1,
+    import_id = excluded.import_id,
+    updated_at = unixepoch()
+`
+
+type QueueLocationReviewParams struct {
+	Name         string        `db:"name" json:"name"`
+	NameKey      string        `db:"name_key" json:"name_key"`
+	LocationType string        `db:"location_type" json:"location_type"`
+	ImportID     sql.NullInt64 `db:"import_id" json:"import_id"`
+}
+
+func (q *Queries) QueueLocationReview(ctx context.Context, arg QueueLocationReviewParams) error {
+	_, err := q.db.ExecContext(ctx, queueLocationReview,
+		arg.Name,
+		arg.NameKey,
+		arg.LocationType,
+		arg.ImportID,
+	)
+	return err
+}
+
+const resetImport = `-- name: ResetImport :exec
+UPDATE imports
+SET completed_at = NULL,
+    failed_at = NULL,
+    next_attempt_at = NULL,
+    last_error = '',
+    dead_at = NULL,
+    runs = 0
+WHERE id = ?
+`
+
+func (q *Queries) ResetImport(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, resetImport, id)
+	return err
+}
+
+const reviveImport = `-- name: ReviveImport :one
+UPDATE imports
+SET dead_at = NULL,
+    failed_at = NULL,
+    next_attempt_at = NULL,
+    runs = 0
+WHERE id = ?
+AND dead_at IS NOT NULL
+RETURNING id, file_path, file_hash, created_at, completed_at, failed_at, runs, source, post_id, post_url, post_text, posted_at, next_attempt_at, last_error, dead_at
+`
+
+func (q *Queries) ReviveImport(ctx context.Context, id int64) (Import, error) {
+	row := q.db.QueryRowContext(ctx, reviveImport, id)
+	var i Import
+	err := row.Scan(
+		&i.ID,
+		&i.FilePath,
+		&i.FileHash,
+		&i.CreatedAt,
+		&i.CompletedAt,
+		&i.FailedAt,
+		&i.Runs,
+		&i.Source,
+		&i.PostID,
+		&i.PostUrl,
+		&i.PostText,
+		&i.PostedAt,
+		&i.NextAttemptAt,
+		&i.LastError,
+		&i.DeadAt,
+	)
+	return i, err
+}
+
+const searchDeliveriesByName = `-- name: SearchDeliveriesByName :many
+SELECT d.id, d.date, d.schedule, d.location_type, d.location_name, d.created_at, d.notes, d.import_id, d.location_id, d.location_base, d.location_sectors, d.location_section, d.location_orientation, d.schedule_kind, d.schedule_start, d.schedule_end, d.approved_at, d.revision, d.updated_at
+FROM deliveries d
+JOIN deliveries_fts fts ON d.id = fts.id
+WHERE d.date > ?
+  AND fts.location_name MATCH ?
+  AND (d.location_sectors = ''
+    OR ',' || d.location_sectors || ',' LIKE CAST(? AS TEXT))
+  AND (d.location_section = ''
+    OR d.location_section LIKE CAST(? AS TEXT))
+GROUP BY d.id
+ORDER BY d.date DESC
+`
+
+type SearchDeliveriesByNameParams struct {
+	Date         UnixTime `db:"date" json:"date"`
+	LocationName string   `db:"location_name" json:"location_name"`
+	Sectors      string   `db:"sectors" json:"sectors"`
+	Section      string   `db:"section" json:"section"`
+}
+
+func (q *Queries) SearchDeliveriesByName(ctx context.Context, arg SearchDeliveriesByNameParams) ([]Delivery, error) {
+	rows, err := q.db.QueryContext(ctx, searchDeliveriesByName,
+		arg.Date,
+		arg.LocationName,
+		arg.Sectors,
+		arg.Section,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Delivery
+	for rows.Next() {
+		var i Delivery
+		if err := rows.Scan(
+			&i.ID,
+			&i.Date,
+			&i.Schedule,
+			&i.LocationType,
+			&i.LocationName,
+			&i.CreatedAt,
+			&i.Notes,
+			&i.ImportID,
+			&i.LocationID,
+			&i.LocationBase,
+			&i.LocationSectors,
+			&i.LocationSection,
+			&i.LocationOrientation,
+			&i.ScheduleKind,
+			&i.ScheduleStart,
+			&i.ScheduleEnd,
+			&i.ApprovedAt,
+			&i.Revision,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setDeliveryLocation = `-- name: SetDeliveryLocation :exec
+UPDATE deliveries
+SET location_id = ?
+WHERE id = ?
+`
+
+type SetDeliveryLocationParams struct {
+	LocationID sql.NullInt64 `db:"location_id" json:"location_id"`
+	ID         int64         `db:"id" json:"id"`
+}
+
+func (q *Queries) SetDeliveryLocation(ctx context.Context, arg SetDeliveryLocationParams) error {
+	_, err := q.db.ExecContext(ctx, setDeliveryLocation, arg.LocationID, arg.ID)
+	return err
+}
+
+const setDeliveryNameParts = `-- name: SetDeliveryNameParts :exec
+UPDATE deliveries
+SET location_base = ?,
+    location_sectors = ?,
+    location_section = ?,
+    location_orientation = ?
+WHERE id = ?
+`
+
+type SetDeliveryNamePartsParams struct {
+	LocationBase        string `db:"location_base" json:"location_base"`
+	LocationSectors     string `db:"location_sectors" json:"location_sectors"`
+	LocationSection     string `db:"location_section" json:"location_section"`
+	LocationOrientation string `db:"location_orientation" json:"location_orientation"`
+	ID                  int64  `db:"id" json:"id"`
+}
+
+func (q *Queries) SetDeliveryNameParts(ctx context.Context, arg SetDeliveryNamePartsParams) error {
+	_, err := q.db.ExecContext(ctx, setDeliveryNameParts,
+		arg.LocationBase,
+		arg.LocationSectors,
+		arg.LocationSection,
+		arg.LocationOrientation,
+		arg.ID,
+	)
+	return err
+}
+
+const updateDelivery = `-- name: UpdateDelivery :one
+UPDATE deliveries
+SET date = ?,
+    schedule = ?,
+    schedule_kind = ?,
+    schedule_start = ?,
+    schedule_end = ?,
+    location_type = ?,
+    location_name = ?,
+    location_base = ?,
+    location_sectors = ?,
+    location_section = ?,
+    location_orientation = ?,
+    notes = ?,
+    location_id = ?,
+    revision = revision + 1,
+    updated_at = unixepoch()
+WHERE id = ?
+RETURNING id, date, schedule, location_type, location_name, created_at, notes, import_id, location_id, location_base, location_sectors, location_section, location_orientation, schedule_kind, schedule_start, schedule_end, approved_at, revision, updated_at
+`
+
+type UpdateDeliveryParams struct {
+	Date                UnixTime      `db:"date" json:"date"`
+	Schedule            string        `db:"schedule" json:"schedule"`
+	ScheduleKind        string        `db:"schedule_kind" json:"schedule_kind"`
+	ScheduleStart       sql.NullInt64 `db:"schedule_start" json:"schedule_start"`
+	ScheduleEnd         sql.NullInt64 `db:"schedule_end" json:"schedule_end"`
+	LocationType        string        `db:"location_type" json:"location_type"`
+	LocationName        string        `db:"location_name" json:"location_name"`
+	LocationBase        string        `db:"location_base" json:"location_base"`
+	LocationSectors     string        `db:"location_sectors" json:"location_sectors"`
+	LocationSection     string        `db:"location_section" json:"location_section"`
+	LocationOrientation string        `db:"location_orientation" json:"location_orientation"`
+	Notes               string        `db:"notes" json:"notes"`
+	LocationID          sql.NullInt64 `db:"location_id" json:"location_id"`
+	ID                  int64         `db:"id" json:"id"`
+}
+
+func (q *Queries) UpdateDelivery(ctx context.Context, arg UpdateDeliveryParams) (Delivery, error) {
+	row := q.db.QueryRowContext(ctx, updateDelivery,
+		arg.Date,
+		arg.Schedule,
+		arg.ScheduleKind,
+		arg.ScheduleStart,
+		arg.ScheduleEnd,
+		arg.LocationType,
+		arg.LocationName,
+		arg.LocationBase,
+		arg.LocationSectors,
+		arg.LocationSection,
+		arg.LocationOrientation,
+		arg.Notes,
+		arg.LocationID,
+		arg.ID,
+	)
+	var i Delivery
+	err := row.Scan(
+		&i.ID,
+		&i.Date,
+		&i.Schedule,
+		&i.LocationType,
+		&i.LocationName,
+		&i.CreatedAt,
+		&i.Notes,
+		&i.ImportID,
+		&i.LocationID,
+		&i.LocationBase,
+		&i.LocationSectors,
+		&i.LocationSection,
+		&i.LocationOrientation,
+		&i.ScheduleKind,
+		&i.ScheduleStart,
+		&i.ScheduleEnd,
+		&i.ApprovedAt,
+		&i.Revision,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
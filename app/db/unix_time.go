@@ -18,6 +18,7 @@ package db
 
 import (
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
 	"time"
 )
@@ -47,6 +48,22 @@ func (ut UnixTime) Value() (driver.Value, error) {
 	return ut.Time.Unix(), nil
 }
 
+// MarshalJSON encodes times in RFC 3339 format, and zero times as null.
+func (ut UnixTime) MarshalJSON() ([]byte, error) {
+	if ut.Time.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(ut.Time.UTC().Format(time.RFC3339))
+}
+
+func (ut *UnixTime) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		ut.Time = time.Time{}
+		return nil
+	}
+	return json.Unmarshal(data, &ut.Time)
+}
+
 func Now() UnixTime {
 	return UnixTime{Time: time.Now().UTC()}
 }
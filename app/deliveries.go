@@ -0,0 +1,235 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"git.cypr.io/oz/aguaxaca/app/db"
+	"git.cypr.io/oz/aguaxaca/gazetteer"
+	"git.cypr.io/oz/aguaxaca/normalizer"
+	"git.cypr.io/oz/aguaxaca/parser"
+	"git.cypr.io/oz/aguaxaca/schedule"
+)
+
+// candidate is a delivery extracted by the parser, with its values read
+// into the forms stored.
+type candidate struct {
+	parser.Delivery
+	date         time.Time
+	schedule     schedule.Schedule
+	locationType string
+	name         normalizer.Name
+}
+
+// newCandidate reads the values of an extracted delivery. Deliveries
+// that can't be stored at all, with an invalid date, or an unknown
+// location type, return an error.
+func newCandidate(delivery parser.Delivery) (*candidate, error) {
+	date, err := time.Parse(DateFormat, delivery.Date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date format '%s'", delivery.Date)
+	}
+
+	locationType, ok := gazetteer.LocationType(delivery.LocationType)
+	if !ok {
+		return nil, fmt.Errorf("unknown location type '%s'", delivery.LocationType)
+	}
+
+	return &candidate{
+		Delivery:     delivery,
+		date:         date,
+		schedule:     schedule.Parse(delivery.Schedule),
+		locationType: locationType,
+		name:         normalizer.Parse(delivery.LocationName),
+	}, nil
+}
+
+// createDelivery stores a delivery, linked to its canonical location, or
+// queues its name for review.
+func (app *App) createDelivery(queries *db.Queries, matcher *gazetteer.Matcher, importID int64, c *candidate) (*db.Delivery, error) {
+	params, err := app.deliveryParams(queries, matcher, importID, c)
+	if err != nil {
+		return nil, err
+	}
+	delivery, err := queries.CreateDelivery(app.Ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create delivery: %w", err)
+	}
+	return &delivery, nil
+}
+
+// updateDelivery replaces the values of a delivery, and counts a new
+// revision of it, unless they are the same.
+func (app *App) updateDelivery(queries *db.Queries, matcher *gazetteer.Matcher, id int64, importID int64, c *candidate) (*db.Delivery, error) {
+	params, err := app.deliveryParams(queries, matcher, importID, c)
+	if err != nil {
+		return nil, err
+	}
+	current, err := queries.GetDelivery(app.Ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery #%d: %w", id, err)
+	}
+	if sameDelivery(current, params) {
+		return &current, nil
+	}
+	delivery, err := queries.UpdateDelivery(app.Ctx, db.UpdateDeliveryParams{
+		Date:                params.Date,
+		Schedule:            params.Schedule,
+		ScheduleKind:        params.ScheduleKind,
+		ScheduleStart:       params.ScheduleStart,
+		ScheduleEnd:         params.ScheduleEnd,
+		LocationType:        params.LocationType,
+		LocationName:        params.LocationName,
+		LocationBase:        params.LocationBase,
+		LocationSectors:     params.LocationSectors,
+		LocationSection:     params.LocationSection,
+		LocationOrientation: params.LocationOrientation,
+		Notes:               params.Notes,
+		LocationID:          params.LocationID,
+		ID:                  id,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update delivery #%d: %w", id, err)
+	}
+	return &delivery, nil
+}
+
+// sameDelivery reports whether d already has the values of params.
+func sameDelivery(d db.Delivery, params db.CreateDeliveryParams) bool {
+	return d.Date.Time.Equal(params.Date.Time) &&
+		d.Schedule == params.Schedule &&
+		d.ScheduleKind == params.ScheduleKind &&
+		d.ScheduleStart == params.ScheduleStart &&
+		d.ScheduleEnd == params.ScheduleEnd &&
+		d.LocationType == params.LocationType &&
+		d.LocationName == params.LocationName &&
+		d.LocationBase == params.LocationBase &&
+		d.LocationSectors == params.LocationSectors &&
+		d.LocationSection == params.LocationSection &&
+		d.LocationOrientation == params.LocationOrientation &&
+		d.Notes == params.Notes &&
+		d.LocationID == params.LocationID
+}
+
+// deliveryParams returns the values stored for a delivery: linked to its
+// canonical location, or with its name queued for review.
+func (app *App) deliveryParams(queries *db.Queries, matcher *gazetteer.Matcher, importID int64, c *candidate) (db.CreateDeliveryParams, error) {
+	importRef := sql.NullInt64{Int64: importID, Valid: true}
+
+	// Resolve the canonical location, or queue the name for review.
+	var locationID sql.NullInt64
+	if id, ok := matcher.Match(c.LocationName); ok {
+		locationID = sql.NullInt64{Int64: id, Valid: true}
+	} else {
+		err := queries.QueueLocationReview(app.Ctx, db.QueueLocationReviewParams{
+			Name:         c.LocationName,
+			NameKey:      gazetteer.Key(c.LocationName),
+			LocationType: c.locationType,
+			ImportID:     importRef,
+		})
+		if err != nil {
+			return db.CreateDeliveryParams{}, fmt.Errorf("failed to queue location review: %w", err)
+		}
+	}
+
+	start, end := scheduleColumns(c.schedule)
+	return db.CreateDeliveryParams{
+		Date:                db.UnixTime{Time: c.date.UTC()},
+		Schedule:            strings.ToLower(c.Schedule),
+		ScheduleKind:        string(c.schedule.Kind),
+		ScheduleStart:       start,
+		ScheduleEnd:         end,
+		LocationType:        c.locationType,
+		LocationName:        c.LocationName,
+		LocationBase:        c.name.Base,
+		LocationSectors:     c.name.SectorList(),
+		LocationSection:     c.name.Section,
+		LocationOrientation: c.name.Orientation,
+		Notes:               c.Notes,
+		ImportID:            importRef,
+		LocationID:          locationID,
+	}, nil
+}
+
+// DeliverySchedule returns the schedule of a delivery, with its time
+// window when known.
+func DeliverySchedule(d db.Delivery) schedule.Schedule {
+	s := schedule.Schedule{Kind: schedule.Kind(d.ScheduleKind)}
+	if d.ScheduleStart.Valid && d.ScheduleEnd.Valid {
+		s.Window = &schedule.Window{
+			Start: schedule.Clock(d.ScheduleStart.Int64),
+			End:   schedule.Clock(d.ScheduleEnd.Int64),
+		}
+	}
+	return s
+}
+
+// scheduleColumns returns the time window of a schedule, as stored in
+// the deliveries table.
+func scheduleColumns(s schedule.Schedule) (start sql.NullInt64, end sql.NullInt64) {
+	if s.Window == nil {
+		return start, end
+	}
+	start = sql.NullInt64{Int64: int64(s.Window.Start), Valid: true}
+	end = sql.NullInt64{Int64: int64(s.Window.End), Valid: true}
+	return start, end
+}
+
+// History summarizes the deliveries of a location: when the last one
+// happened, how often they happen, and when the next one is expected.
+// Interval, and Next are zero with less than two delivery dates.
+type History struct {
+	Deliveries []db.Delivery
+	Last       time.Time
+	Interval   time.Duration
+	Next       time.Time
+}
+
+// NewHistory summarizes deliveries, sorted from the latest: the average
+// interval is between distinct dates, as a notice can list a location
+// more than once on a day.
+func NewHistory(deliveries []db.Delivery) History {
+	h := History{Deliveries: deliveries}
+	if len(deliveries) == 0 {
+		return h
+	}
+
+	h.Last = deliveries[0].Date.Time
+	first, dates := h.Last, 1
+	for _, d := range deliveries[1:] {
+		if d.Date.Time.Before(first) {
+			first = d.Date.Time
+			dates++
+		}
+	}
+	if dates < 2 {
+		return h
+	}
+
+	h.Interval = h.Last.Sub(first) / time.Duration(dates-1)
+	h.Next = h.Last.Add(h.Interval.Round(24 * time.Hour))
+	return h
+}
+
+// IntervalDays is the average interval, in days.
+func (h History) IntervalDays() float64 {
+	return h.Interval.Hours() / 24
+}
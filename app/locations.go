@@ -0,0 +1,201 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"git.cypr.io/oz/aguaxaca/app/db"
+	"git.cypr.io/oz/aguaxaca/gazetteer"
+	"git.cypr.io/oz/aguaxaca/normalizer"
+)
+
+// Locations manages the gazetteer of canonical locations, their
+// aliases, and the review queue of unmatched names.
+type Locations struct {
+	app *App
+	log *slog.Logger
+}
+
+func (app *App) NewLocations() *Locations {
+	return &Locations{
+		app: app,
+		log: app.Logger.With("component", "locations"),
+	}
+}
+
+// LoadMatcher builds a matcher with all known aliases.
+func LoadMatcher(app *App, queries *db.Queries) (*gazetteer.Matcher, error) {
+	aliases, err := queries.ListLocationAliases(app.Ctx)
+	if err != nil {
+		return nil, fmt.Errorf("ListLocationAliases: %w", err)
+	}
+
+	matcher := gazetteer.NewMatcher()
+	for _, alias := range aliases {
+		matcher.Add(alias.LocationID, alias.Alias)
+	}
+	return matcher, nil
+}
+
+// List returns all canonical locations.
+func (l *Locations) List() ([]db.Location, error) {
+	return db.New(l.app.DB).ListLocations(l.app.Ctx)
+}
+
+// Reviews returns the names that matched no location, most frequent
+// first.
+func (l *Locations) Reviews() ([]db.LocationReview, error) {
+	return db.New(l.app.DB).ListLocationReviews(l.app.Ctx)
+}
+
+// Add creates a location, with its own name as first alias, and links
+// matching deliveries to it.
+func (l *Locations) Add(name string, locationType string) (*db.Location, error) {
+	name = strings.TrimSpace(name)
+	slug := gazetteer.Slug(name)
+	if slug == "" {
+		return nil, fmt.Errorf("invalid location name '%s'", name)
+	}
+
+	canonicalType, ok := gazetteer.LocationType(locationType)
+	if !ok {
+		return nil, fmt.Errorf("unknown location type '%s', use one of: %s",
+			locationType, strings.Join(gazetteer.LocationTypes, ", "))
+	}
+
+	queries := db.New(l.app.DB)
+	location, err := queries.CreateLocation(l.app.Ctx, db.CreateLocationParams{
+		Slug:         slug,
+		Name:         name,
+		LocationType: canonicalType,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("CreateLocation '%s': %w", name, err)
+	}
+	l.log.Info("new location", "id", location.ID, "slug", location.Slug)
+
+	if err := l.AddAlias(location.Slug, name); err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
+
+// AddAlias adds another name to a location, removes it from the review
+// queue, and links matching deliveries to the location.
+func (l *Locations) AddAlias(slug string, alias string) error {
+	queries := db.New(l.app.DB)
+	location, err := queries.GetLocationBySlug(l.app.Ctx, slug)
+	if err != nil {
+		return fmt.Errorf("location '%s': %w", slug, err)
+	}
+
+	key := gazetteer.Key(alias)
+	if key == "" {
+		return fmt.Errorf("invalid alias '%s'", alias)
+	}
+	_, err = queries.CreateLocationAlias(l.app.Ctx, db.CreateLocationAliasParams{
+		LocationID: location.ID,
+		Alias:      strings.TrimSpace(alias),
+		AliasKey:   key,
+	})
+	if err != nil {
+		return fmt.Errorf("CreateLocationAlias '%s': %w", alias, err)
+	}
+	if err := queries.DeleteLocationReview(l.app.Ctx, key); err != nil {
+		return fmt.Errorf("DeleteLocationReview '%s': %w", alias, err)
+	}
+	l.log.Info("new alias", "location", location.Slug, "alias", alias)
+
+	_, err = l.Relink()
+	return err
+}
+
+// Relink matches deliveries without a location again, and returns how
+// many were linked.
+func (l *Locations) Relink() (int, error) {
+	queries := db.New(l.app.DB)
+	matcher, err := LoadMatcher(l.app, queries)
+	if err != nil {
+		return 0, err
+	}
+
+	deliveries, err := queries.ListUnlinkedDeliveries(l.app.Ctx)
+	if err != nil {
+		return 0, fmt.Errorf("ListUnlinkedDeliveries: %w", err)
+	}
+
+	count := 0
+	for _, delivery := range deliveries {
+		locationID, ok := matcher.Match(delivery.LocationName)
+		if !ok {
+			continue
+		}
+		err := queries.SetDeliveryLocation(l.app.Ctx, db.SetDeliveryLocationParams{
+			LocationID: sql.NullInt64{Int64: locationID, Valid: true},
+			ID:         delivery.ID,
+		})
+		if err != nil {
+			return count, fmt.Errorf("SetDeliveryLocation #%d: %w", delivery.ID, err)
+		}
+		if err := queries.DeleteLocationReview(l.app.Ctx, gazetteer.Key(delivery.LocationName)); err != nil {
+			return count, fmt.Errorf("DeleteLocationReview '%s': %w", delivery.LocationName, err)
+		}
+		count++
+	}
+	l.log.Info("linked deliveries", "count", count)
+
+	return count, nil
+}
+
+// Normalize splits the location names of all deliveries again (see
+// normalizer.Parse), and returns how many were updated.
+func (l *Locations) Normalize() (int, error) {
+	queries := db.New(l.app.DB)
+	deliveries, err := queries.ListAllDeliveries(l.app.Ctx)
+	if err != nil {
+		return 0, fmt.Errorf("ListAllDeliveries: %w", err)
+	}
+
+	count := 0
+	for _, delivery := range deliveries {
+		name := normalizer.Parse(delivery.LocationName)
+		params := db.SetDeliveryNamePartsParams{
+			LocationBase:        name.Base,
+			LocationSectors:     name.SectorList(),
+			LocationSection:     name.Section,
+			LocationOrientation: name.Orientation,
+			ID:                  delivery.ID,
+		}
+		if params.LocationBase == delivery.LocationBase &&
+			params.LocationSectors == delivery.LocationSectors &&
+			params.LocationSection == delivery.LocationSection &&
+			params.LocationOrientation == delivery.LocationOrientation {
+			continue
+		}
+		if err := queries.SetDeliveryNameParts(l.app.Ctx, params); err != nil {
+			return count, fmt.Errorf("SetDeliveryNameParts #%d: %w", delivery.ID, err)
+		}
+		count++
+	}
+	l.log.Info("normalized deliveries", "count", count)
+
+	return count, nil
+}
@@ -0,0 +1,213 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"git.cypr.io/oz/aguaxaca/app/db"
+)
+
+//go:embed sql/migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration files are named like "0002_imports_post.up.sql", and
+// "0002_imports_post.down.sql".
+var migrationFileRe = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Applied migrations are recorded in the schema_migrations table.
+const migrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+  version    INTEGER PRIMARY KEY,
+  name       TEXT NOT NULL,
+  applied_at TIMESTAMP NOT NULL
+)`
+
+// Migration is a versioned change of the DB schema.
+type Migration struct {
+	Version   int
+	Name      string
+	Up        string
+	Down      string
+	AppliedAt *db.UnixTime // nil when pending
+}
+
+// Migrator applies, or reverts, the embedded migrations, each in its own
+// transaction.
+type Migrator struct {
+	app *App
+	log *slog.Logger
+}
+
+func (app *App) NewMigrator() *Migrator {
+	return &Migrator{
+		app: app,
+		log: app.Logger.With("component", "migrator"),
+	}
+}
+
+// Status returns all migrations, in order, with the time they were
+// applied.
+func (m *Migrator) Status() ([]Migration, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := m.app.DB.ExecContext(m.app.Ctx, migrationsTable); err != nil {
+		return nil, fmt.Errorf("can't create schema_migrations: %w", err)
+	}
+	rows, err := m.app.DB.QueryContext(m.app.Ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("can't read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[int]db.UnixTime{}
+	for rows.Next() {
+		var version int
+		var appliedAt db.UnixTime
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range migrations {
+		if appliedAt, ok := applied[migrations[i].Version]; ok {
+			migrations[i].AppliedAt = &appliedAt
+		}
+	}
+	return migrations, nil
+}
+
+// Up applies all pending migrations, and returns how many were applied.
+func (m *Migrator) Up() (int, error) {
+	migrations, err := m.Status()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, migration := range migrations {
+		if migration.AppliedAt != nil {
+			continue
+		}
+		err := m.apply(migration.Up, `INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, unixepoch())`,
+			migration.Version, migration.Name)
+		if err != nil {
+			return count, fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		m.log.Info("applied migration", "version", migration.Version, "name", migration.Name)
+		count++
+	}
+	return count, nil
+}
+
+// Down reverts the latest applied migration, and returns it, or nil if
+// none was applied.
+func (m *Migrator) Down() (*Migration, error) {
+	migrations, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		migration := migrations[i]
+		if migration.AppliedAt == nil {
+			continue
+		}
+		err := m.apply(migration.Down, `DELETE FROM schema_migrations WHERE version = ?`, migration.Version)
+		if err != nil {
+			return nil, fmt.Errorf("reverting migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		m.log.Info("reverted migration", "version", migration.Version, "name", migration.Name)
+		return &migration, nil
+	}
+	return nil, nil
+}
+
+// apply runs a migration's SQL, and records it in schema_migrations, in
+// a single transaction.
+func (m *Migrator) apply(migration string, record string, args ...any) error {
+	tx, err := m.app.DB.BeginTx(m.app.Ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(m.app.Ctx, migration); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(m.app.Ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// loadMigrations reads the embedded migrations, sorted by version.
+func loadMigrations() ([]Migration, error) {
+	files, err := fs.Glob(migrationFiles, "sql/migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, file := range files {
+		m := migrationFileRe.FindStringSubmatch(path.Base(file))
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name '%s'", file)
+		}
+		version, _ := strconv.Atoi(m[1])
+		content, err := migrationFiles.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		} else if migration.Name != m[2] {
+			return nil, fmt.Errorf("migration %04d has two names: '%s', and '%s'", version, migration.Name, m[2])
+		}
+		if m[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return a.Version - b.Version })
+	return migrations, nil
+}
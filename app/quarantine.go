@@ -0,0 +1,133 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"fmt"
+	"log/slog"
+
+	"git.cypr.io/oz/aguaxaca/app/db"
+	"git.cypr.io/oz/aguaxaca/parser"
+)
+
+// Quarantine manages deliveries that failed validation.
+type Quarantine struct {
+	app *App
+	log *slog.Logger
+}
+
+func (app *App) NewQuarantine() *Quarantine {
+	return &Quarantine{
+		app: app,
+		log: app.Logger.With("component", "quarantine"),
+	}
+}
+
+// List returns all quarantined deliveries.
+func (q *Quarantine) List() ([]db.Quarantine, error) {
+	return db.New(q.app.DB).ListQuarantine(q.app.Ctx)
+}
+
+// ListByImport returns the quarantined deliveries of an import.
+func (q *Quarantine) ListByImport(importID int64) ([]db.Quarantine, error) {
+	return db.New(q.app.DB).ListQuarantineByImport(q.app.Ctx, importID)
+}
+
+// Approve stores a quarantined delivery, without validation rules. The
+// non-empty fields of fix replace the extracted values.
+func (q *Quarantine) Approve(actor string, id int64, fix parser.Delivery) (*db.Delivery, error) {
+	var delivery *db.Delivery
+	err := q.app.inTx(func(queries *db.Queries) error {
+		row, err := queries.GetQuarantinedDelivery(q.app.Ctx, id)
+		if err != nil {
+			return fmt.Errorf("quarantined delivery #%d: %w", id, err)
+		}
+
+		c, err := newCandidate(fixDelivery(row, fix))
+		if err != nil {
+			return err
+		}
+		matcher, err := LoadMatcher(q.app, queries)
+		if err != nil {
+			return err
+		}
+		if delivery, err = q.app.createDelivery(queries, matcher, row.ImportID, c); err != nil {
+			return err
+		}
+		approved, err := queries.ApproveDelivery(q.app.Ctx, delivery.ID)
+		if err != nil {
+			return fmt.Errorf("ApproveDelivery #%d: %w", delivery.ID, err)
+		}
+		delivery = &approved
+		if err := queries.DeleteQuarantinedDelivery(q.app.Ctx, id); err != nil {
+			return fmt.Errorf("DeleteQuarantinedDelivery #%d: %w", id, err)
+		}
+		return q.app.audit(queries, actor, ActionApproveQuarantine, row.ImportID, delivery.ID, row, delivery)
+	})
+	if err != nil {
+		return nil, err
+	}
+	q.log.Info("approved delivery", "actor", actor, "quarantine", id, "delivery", delivery.ID)
+	return delivery, nil
+}
+
+// Delete drops a quarantined delivery.
+func (q *Quarantine) Delete(actor string, id int64) error {
+	err := q.app.inTx(func(queries *db.Queries) error {
+		row, err := queries.GetQuarantinedDelivery(q.app.Ctx, id)
+		if err != nil {
+			return fmt.Errorf("quarantined delivery #%d: %w", id, err)
+		}
+		if err := queries.DeleteQuarantinedDelivery(q.app.Ctx, id); err != nil {
+			return fmt.Errorf("DeleteQuarantinedDelivery #%d: %w", id, err)
+		}
+		return q.app.audit(queries, actor, ActionDeleteQuarantine, row.ImportID, 0, row, nil)
+	})
+	if err != nil {
+		return err
+	}
+	q.log.Info("deleted delivery", "actor", actor, "quarantine", id)
+	return nil
+}
+
+// fixDelivery returns the values of a quarantined delivery, replaced by
+// the non-empty fields of fix.
+func fixDelivery(row db.Quarantine, fix parser.Delivery) parser.Delivery {
+	delivery := parser.Delivery{
+		Date:         row.Date,
+		Schedule:     row.Schedule,
+		LocationType: row.LocationType,
+		LocationName: row.LocationName,
+		Notes:        row.Notes,
+	}
+	if fix.Date != "" {
+		delivery.Date = fix.Date
+	}
+	if fix.Schedule != "" {
+		delivery.Schedule = fix.Schedule
+	}
+	if fix.LocationType != "" {
+		delivery.LocationType = fix.LocationType
+	}
+	if fix.LocationName != "" {
+		delivery.LocationName = fix.LocationName
+	}
+	if fix.Notes != "" {
+		delivery.Notes = fix.Notes
+	}
+	return delivery
+}
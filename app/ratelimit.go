@@ -0,0 +1,88 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TokenBucket limits the rate of API requests. The bucket holds up to
+// burst tokens, refilled continuously at a rate of perMinute tokens per
+// minute, and each request takes a token. A nil bucket doesn't limit.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket builds a full bucket, or nil when perMinute is 0.
+func NewTokenBucket(perMinute int, burst int) *TokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	burst = max(burst, 1)
+	return &TokenBucket{
+		rate:   float64(perMinute) / 60,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a token is available, and takes it, or until ctx
+// is done.
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	if b == nil {
+		return ctx.Err()
+	}
+
+	for {
+		delay, ok := b.take()
+		if ok {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// take refills the bucket, and takes a token. When it's empty, take
+// returns how long to wait for the next token instead.
+func (b *TokenBucket) take() (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second)), false
+}
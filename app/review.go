@@ -0,0 +1,356 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"git.cypr.io/oz/aguaxaca/app/db"
+	"git.cypr.io/oz/aguaxaca/gazetteer"
+	"git.cypr.io/oz/aguaxaca/parser"
+)
+
+// Actions recorded in the audit log.
+const (
+	ActionAdd               = "add"
+	ActionEdit              = "edit"
+	ActionDelete            = "delete"
+	ActionApprove           = "approve"
+	ActionApproveQuarantine = "approve_quarantined"
+	ActionDeleteQuarantine  = "delete_quarantined"
+	ActionReviveImport      = "revive_import"
+)
+
+// Review lets people correct the deliveries extracted from an import.
+// Every correction is recorded in the audit log, with its actor.
+type Review struct {
+	app *App
+	log *slog.Logger
+}
+
+func (app *App) NewReview() *Review {
+	return &Review{
+		app: app,
+		log: app.Logger.With("component", "review"),
+	}
+}
+
+// ImportReview is an import, and everything extracted from it.
+type ImportReview struct {
+	Import     db.Import
+	Deliveries []db.Delivery
+	Quarantine []db.Quarantine
+	AuditLog   []db.AuditLog
+}
+
+// Imports returns the latest imports.
+func (r *Review) Imports(limit int64) ([]db.Import, error) {
+	return db.New(r.app.DB).ListImports(r.app.Ctx, limit)
+}
+
+// Import returns an import, its deliveries, quarantined deliveries, and
+// corrections.
+func (r *Review) Import(id int64) (*ImportReview, error) {
+	queries := db.New(r.app.DB)
+	im, err := queries.GetImport(r.app.Ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("import #%d: %w", id, err)
+	}
+
+	review := &ImportReview{Import: im}
+	review.Deliveries, err = queries.ListDeliveriesByImport(r.app.Ctx, sql.NullInt64{Int64: id, Valid: true})
+	if err != nil {
+		return nil, fmt.Errorf("ListDeliveriesByImport #%d: %w", id, err)
+	}
+	review.Quarantine, err = queries.ListQuarantineByImport(r.app.Ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("ListQuarantineByImport #%d: %w", id, err)
+	}
+	review.AuditLog, err = queries.ListAuditLogByImport(r.app.Ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("ListAuditLogByImport #%d: %w", id, err)
+	}
+	return review, nil
+}
+
+// DeadImports returns the imports that failed too many times, and are
+// not analyzed anymore.
+func (r *Review) DeadImports() ([]db.Import, error) {
+	return db.New(r.app.DB).ListDeadImports(r.app.Ctx)
+}
+
+// ReviveImport queues a dead import for analysis again, with MaxRuns
+// new attempts.
+func (r *Review) ReviveImport(actor string, id int64) (*db.Import, error) {
+	var revived db.Import
+	err := r.app.inTx(func(queries *db.Queries) error {
+		before, err := queries.GetImport(r.app.Ctx, id)
+		if err != nil {
+			return fmt.Errorf("import #%d: %w", id, err)
+		}
+		if before.DeadAt == nil {
+			return fmt.Errorf("import #%d is not dead", id)
+		}
+		if revived, err = queries.ReviveImport(r.app.Ctx, id); err != nil {
+			return fmt.Errorf("ReviveImport #%d: %w", id, err)
+		}
+		return r.app.audit(queries, actor, ActionReviveImport, id, 0, before, revived)
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.log.Info("revived import", "actor", actor, "import", id)
+	return &revived, nil
+}
+
+// AddDelivery adds a delivery missed by the parser to an import.
+func (r *Review) AddDelivery(actor string, importID int64, d parser.Delivery) (*db.Delivery, error) {
+	c, err := newReviewedCandidate(d)
+	if err != nil {
+		return nil, err
+	}
+
+	var delivery *db.Delivery
+	err = r.app.inTx(func(queries *db.Queries) error {
+		if _, err := queries.GetImport(r.app.Ctx, importID); err != nil {
+			return fmt.Errorf("import #%d: %w", importID, err)
+		}
+		matcher, err := LoadMatcher(r.app, queries)
+		if err != nil {
+			return err
+		}
+		if delivery, err = r.app.createDelivery(queries, matcher, importID, c); err != nil {
+			return err
+		}
+		approved, err := queries.ApproveDelivery(r.app.Ctx, delivery.ID)
+		if err != nil {
+			return fmt.Errorf("ApproveDelivery #%d: %w", delivery.ID, err)
+		}
+		delivery = &approved
+		return r.app.audit(queries, actor, ActionAdd, importID, delivery.ID, nil, delivery)
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.log.Info("added delivery", "actor", actor, "import", importID, "delivery", delivery.ID)
+	return delivery, nil
+}
+
+// UpdateDelivery replaces the values of a delivery, and approves it.
+func (r *Review) UpdateDelivery(actor string, id int64, d parser.Delivery) (*db.Delivery, error) {
+	c, err := newReviewedCandidate(d)
+	if err != nil {
+		return nil, err
+	}
+
+	var delivery *db.Delivery
+	err = r.app.inTx(func(queries *db.Queries) error {
+		before, err := getReviewableDelivery(r.app, queries, id)
+		if err != nil {
+			return err
+		}
+		matcher, err := LoadMatcher(r.app, queries)
+		if err != nil {
+			return err
+		}
+		if _, err = r.app.updateDelivery(queries, matcher, id, before.ImportID.Int64, c); err != nil {
+			return err
+		}
+		approved, err := queries.ApproveDelivery(r.app.Ctx, id)
+		if err != nil {
+			return fmt.Errorf("ApproveDelivery #%d: %w", id, err)
+		}
+		delivery = &approved
+		return r.app.audit(queries, actor, ActionEdit, before.ImportID.Int64, id, before, delivery)
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.log.Info("edited delivery", "actor", actor, "delivery", id)
+	return delivery, nil
+}
+
+// ApproveDelivery marks a delivery as checked by a reviewer.
+func (r *Review) ApproveDelivery(actor string, id int64) error {
+	err := r.app.inTx(func(queries *db.Queries) error {
+		before, err := getReviewableDelivery(r.app, queries, id)
+		if err != nil {
+			return err
+		}
+		after, err := queries.ApproveDelivery(r.app.Ctx, id)
+		if err != nil {
+			return fmt.Errorf("ApproveDelivery #%d: %w", id, err)
+		}
+		return r.app.audit(queries, actor, ActionApprove, before.ImportID.Int64, id, before, after)
+	})
+	if err != nil {
+		return err
+	}
+	r.log.Info("approved delivery", "actor", actor, "delivery", id)
+	return nil
+}
+
+// DeleteDelivery removes a delivery.
+func (r *Review) DeleteDelivery(actor string, id int64) error {
+	err := r.app.inTx(func(queries *db.Queries) error {
+		before, err := getReviewableDelivery(r.app, queries, id)
+		if err != nil {
+			return err
+		}
+		if err := queries.DeleteDelivery(r.app.Ctx, id); err != nil {
+			return fmt.Errorf("DeleteDelivery #%d: %w", id, err)
+		}
+		return r.app.audit(queries, actor, ActionDelete, before.ImportID.Int64, id, before, nil)
+	})
+	if err != nil {
+		return err
+	}
+	r.log.Info("deleted delivery", "actor", actor, "delivery", id)
+	return nil
+}
+
+// getReviewableDelivery returns a delivery that belongs to an import.
+func getReviewableDelivery(app *App, queries *db.Queries, id int64) (*db.Delivery, error) {
+	delivery, err := queries.GetDelivery(app.Ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("delivery #%d: %w", id, err)
+	}
+	if !delivery.ImportID.Valid {
+		return nil, fmt.Errorf("delivery #%d has no import", id)
+	}
+	return &delivery, nil
+}
+
+// newReviewedCandidate reads the values of a delivery given by a
+// reviewer.
+func newReviewedCandidate(d parser.Delivery) (*candidate, error) {
+	d.Date = strings.TrimSpace(d.Date)
+	d.Schedule = strings.TrimSpace(d.Schedule)
+	d.LocationType = strings.TrimSpace(d.LocationType)
+	d.LocationName = strings.TrimSpace(d.LocationName)
+	d.Notes = strings.TrimSpace(d.Notes)
+	if d.LocationName == "" {
+		return nil, fmt.Errorf("missing location name")
+	}
+	return newCandidate(d)
+}
+
+// reviewedKeys returns the extraction keys of an import's reviewed
+// deliveries: the approved ones, and the previous values of the ones
+// that were edited, or deleted by a reviewer.
+func (app *App) reviewedKeys(queries *db.Queries, importID int64, deliveries []db.Delivery) (map[string]bool, error) {
+	keys := map[string]bool{}
+	for _, d := range deliveries {
+		if d.ApprovedAt != nil {
+			keys[deliveryKey(d)] = true
+		}
+	}
+
+	entries, err := queries.ListAuditLogByImport(app.Ctx, importID)
+	if err != nil {
+		return nil, fmt.Errorf("ListAuditLogByImport #%d: %w", importID, err)
+	}
+	for _, e := range entries {
+		if e.Before == "" || e.Action == ActionReviveImport {
+			continue
+		}
+		// A delivery, or a quarantined delivery.
+		var row struct {
+			Date         string `json:"date"`
+			Schedule     string `json:"schedule"`
+			LocationName string `json:"location_name"`
+		}
+		if err := json.Unmarshal([]byte(e.Before), &row); err != nil {
+			return nil, fmt.Errorf("audit entry #%d: %w", e.ID, err)
+		}
+		keys[extractionKey(row.Date, row.Schedule, row.LocationName)] = true
+	}
+	return keys, nil
+}
+
+// extractionKey identifies an extracted delivery across analyses of an
+// import. Dates are either "YYYY-MM-DD", or RFC 3339 timestamps.
+func extractionKey(date string, schedule string, locationName string) string {
+	date = strings.TrimSpace(date)
+	if t, err := time.Parse(time.RFC3339, date); err == nil {
+		date = t.Format(DateFormat)
+	}
+	return date + "|" + strings.ToLower(strings.TrimSpace(schedule)) + "|" + gazetteer.Key(locationName)
+}
+
+func deliveryKey(d db.Delivery) string {
+	return extractionKey(d.Date.Time.Format(DateFormat), d.Schedule, d.LocationName)
+}
+
+// inTx runs fn in a transaction, committed when fn returns no error.
+func (app *App) inTx(fn func(queries *db.Queries) error) error {
+	tx, err := app.DB.BeginTx(app.Ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(db.New(app.DB).WithTx(tx)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// audit records a correction. before and after are snapshots of the
+// changed row, or nil.
+func (app *App) audit(queries *db.Queries, actor string, action string, importID int64, deliveryID int64, before any, after any) error {
+	params := db.CreateAuditEntryParams{
+		Actor:    actor,
+		Action:   action,
+		ImportID: importID,
+	}
+	if deliveryID != 0 {
+		params.DeliveryID = sql.NullInt64{Int64: deliveryID, Valid: true}
+	}
+
+	var err error
+	if params.Before, err = snapshot(before); err != nil {
+		return err
+	}
+	if params.After, err = snapshot(after); err != nil {
+		return err
+	}
+
+	if err := queries.CreateAuditEntry(app.Ctx, params); err != nil {
+		return fmt.Errorf("CreateAuditEntry: %w", err)
+	}
+	return nil
+}
+
+func snapshot(row any) (string, error) {
+	if row == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(row)
+	if err != nil {
+		return "", fmt.Errorf("audit snapshot: %w", err)
+	}
+	return string(data), nil
+}
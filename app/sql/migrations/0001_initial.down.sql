@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS deliveries_au;
+DROP TRIGGER IF EXISTS deliveries_ad;
+DROP TRIGGER IF EXISTS deliveries_ai;
+DROP TABLE IF EXISTS deliveries_fts;
+DROP TABLE IF EXISTS imports;
+DROP TABLE IF EXISTS deliveries;
@@ -0,0 +1,5 @@
+ALTER TABLE imports DROP COLUMN posted_at;
+ALTER TABLE imports DROP COLUMN post_text;
+ALTER TABLE imports DROP COLUMN post_url;
+ALTER TABLE imports DROP COLUMN post_id;
+ALTER TABLE imports DROP COLUMN source;
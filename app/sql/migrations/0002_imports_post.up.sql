@@ -0,0 +1,7 @@
+-- The source, and post_* columns of imports describe the public notice
+-- (a tweet, ...) the image came from.
+ALTER TABLE imports ADD COLUMN source TEXT NOT NULL DEFAULT '';
+ALTER TABLE imports ADD COLUMN post_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE imports ADD COLUMN post_url TEXT NOT NULL DEFAULT '';
+ALTER TABLE imports ADD COLUMN post_text TEXT NOT NULL DEFAULT '';
+ALTER TABLE imports ADD COLUMN posted_at TIMESTAMP DEFAULT NULL;
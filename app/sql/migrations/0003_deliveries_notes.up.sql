@@ -0,0 +1 @@
+ALTER TABLE deliveries ADD COLUMN notes TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,17 @@
+-- analyses stores the raw response of every parser run on an import, to
+-- audit extractions, and compare prompt versions (prompt_hash).
+CREATE TABLE analyses (
+  id            INTEGER PRIMARY KEY,
+  import_id     INTEGER NOT NULL REFERENCES imports(id),
+  prompt_hash   TEXT NOT NULL,
+  model         TEXT NOT NULL,
+  response      TEXT NOT NULL,
+  input_tokens  INTEGER NOT NULL DEFAULT 0,
+  output_tokens INTEGER NOT NULL DEFAULT 0,
+  latency_ms    INTEGER NOT NULL DEFAULT 0,
+  outcome       TEXT NOT NULL,
+  error         TEXT NOT NULL DEFAULT '',
+  created_at    TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_analyses_import_id ON analyses(import_id);
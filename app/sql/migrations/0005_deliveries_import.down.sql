@@ -0,0 +1,2 @@
+DROP INDEX idx_deliveries_import_id;
+ALTER TABLE deliveries DROP COLUMN import_id;
@@ -0,0 +1,3 @@
+ALTER TABLE deliveries ADD COLUMN import_id INTEGER DEFAULT NULL REFERENCES imports(id);
+
+CREATE INDEX idx_deliveries_import_id ON deliveries(import_id);
@@ -0,0 +1,5 @@
+DROP INDEX idx_deliveries_location_id;
+ALTER TABLE deliveries DROP COLUMN location_id;
+DROP TABLE location_reviews;
+DROP TABLE location_aliases;
+DROP TABLE locations;
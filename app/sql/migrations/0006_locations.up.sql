@@ -0,0 +1,34 @@
+-- locations is the gazetteer of canonical locations (colonias, ...).
+CREATE TABLE locations (
+  id            INTEGER PRIMARY KEY,
+  slug          TEXT UNIQUE NOT NULL,
+  name          TEXT NOT NULL,
+  location_type TEXT NOT NULL,
+  created_at    TIMESTAMP NOT NULL
+);
+
+-- location_aliases are the names of a location, as found in notices.
+-- alias_key is the folded name used for matching (see gazetteer.Key).
+CREATE TABLE location_aliases (
+  id          INTEGER PRIMARY KEY,
+  location_id INTEGER NOT NULL REFERENCES locations(id),
+  alias       TEXT NOT NULL,
+  alias_key   TEXT UNIQUE NOT NULL,
+  created_at  TIMESTAMP NOT NULL
+);
+
+-- location_reviews is the queue of names that matched no location.
+CREATE TABLE location_reviews (
+  id            INTEGER PRIMARY KEY,
+  name          TEXT NOT NULL,
+  name_key      TEXT UNIQUE NOT NULL,
+  location_type TEXT NOT NULL,
+  occurrences   INTEGER NOT NULL DEFAULT 1,
+  import_id     INTEGER DEFAULT NULL REFERENCES imports(id),
+  created_at    TIMESTAMP NOT NULL,
+  updated_at    TIMESTAMP NOT NULL
+);
+
+ALTER TABLE deliveries ADD COLUMN location_id INTEGER DEFAULT NULL REFERENCES locations(id);
+
+CREATE INDEX idx_deliveries_location_id ON deliveries(location_id);
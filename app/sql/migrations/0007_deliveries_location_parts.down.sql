@@ -0,0 +1,4 @@
+ALTER TABLE deliveries DROP COLUMN location_orientation;
+ALTER TABLE deliveries DROP COLUMN location_section;
+ALTER TABLE deliveries DROP COLUMN location_sectors;
+ALTER TABLE deliveries DROP COLUMN location_base;
@@ -0,0 +1,6 @@
+-- The parts of deliveries.location_name (see normalizer.Parse), with
+-- sectors separated by commas.
+ALTER TABLE deliveries ADD COLUMN location_base TEXT NOT NULL DEFAULT '';
+ALTER TABLE deliveries ADD COLUMN location_sectors TEXT NOT NULL DEFAULT '';
+ALTER TABLE deliveries ADD COLUMN location_section TEXT NOT NULL DEFAULT '';
+ALTER TABLE deliveries ADD COLUMN location_orientation TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+ALTER TABLE deliveries DROP COLUMN schedule_end;
+ALTER TABLE deliveries DROP COLUMN schedule_start;
+ALTER TABLE deliveries DROP COLUMN schedule_kind;
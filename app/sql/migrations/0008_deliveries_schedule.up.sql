@@ -0,0 +1,6 @@
+-- schedule_kind is the kind of schedule ("unknown" if missing from
+-- schedule.Vocabulary), and schedule_start, schedule_end its time
+-- window, in minutes after midnight in America/Mexico_City.
+ALTER TABLE deliveries ADD COLUMN schedule_kind TEXT NOT NULL DEFAULT '';
+ALTER TABLE deliveries ADD COLUMN schedule_start INTEGER DEFAULT NULL;
+ALTER TABLE deliveries ADD COLUMN schedule_end INTEGER DEFAULT NULL;
@@ -0,0 +1,15 @@
+-- quarantine holds deliveries that failed validation, as extracted by
+-- the parser, until an operator approves, fixes, or deletes them.
+CREATE TABLE quarantine (
+  id            INTEGER PRIMARY KEY,
+  import_id     INTEGER NOT NULL REFERENCES imports(id),
+  date          TEXT NOT NULL,
+  schedule      TEXT NOT NULL,
+  location_type TEXT NOT NULL,
+  location_name TEXT NOT NULL,
+  notes         TEXT NOT NULL DEFAULT '',
+  reason        TEXT NOT NULL,
+  created_at    TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_quarantine_import_id ON quarantine(import_id);
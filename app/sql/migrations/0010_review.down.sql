@@ -0,0 +1,2 @@
+DROP TABLE audit_log;
+ALTER TABLE deliveries DROP COLUMN approved_at;
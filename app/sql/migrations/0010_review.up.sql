@@ -0,0 +1,17 @@
+-- approved_at is set when a reviewer approves, adds, or edits a delivery.
+ALTER TABLE deliveries ADD COLUMN approved_at TIMESTAMP DEFAULT NULL;
+
+-- audit_log records the corrections of reviewers. before and after are
+-- JSON snapshots of the changed row (a delivery, or a quarantined one).
+CREATE TABLE audit_log (
+  id          INTEGER PRIMARY KEY,
+  actor       TEXT NOT NULL,
+  action      TEXT NOT NULL,
+  import_id   INTEGER NOT NULL REFERENCES imports(id),
+  delivery_id INTEGER DEFAULT NULL,
+  before      TEXT NOT NULL DEFAULT '',
+  after       TEXT NOT NULL DEFAULT '',
+  created_at  TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_audit_log_import_id ON audit_log(import_id);
@@ -0,0 +1,3 @@
+ALTER TABLE imports DROP COLUMN dead_at;
+ALTER TABLE imports DROP COLUMN last_error;
+ALTER TABLE imports DROP COLUMN next_attempt_at;
@@ -0,0 +1,13 @@
+-- Failed imports are analyzed again after next_attempt_at (exponential
+-- backoff), until they fail too many times: then dead_at is set, and
+-- they are not retried unless revived. last_error is the error of their
+-- latest analysis.
+ALTER TABLE imports ADD COLUMN next_attempt_at TIMESTAMP DEFAULT NULL;
+ALTER TABLE imports ADD COLUMN last_error TEXT NOT NULL DEFAULT '';
+ALTER TABLE imports ADD COLUMN dead_at TIMESTAMP DEFAULT NULL;
+
+-- Imports that already failed 3 times were not retried anymore.
+UPDATE imports SET dead_at = failed_at
+WHERE completed_at IS NULL
+AND failed_at IS NOT NULL
+AND runs >= 3;
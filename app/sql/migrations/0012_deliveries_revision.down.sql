@@ -0,0 +1,2 @@
+ALTER TABLE deliveries DROP COLUMN updated_at;
+ALTER TABLE deliveries DROP COLUMN revision;
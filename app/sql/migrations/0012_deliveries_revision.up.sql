@@ -0,0 +1,11 @@
+-- Deliveries are changed in place, by reviewers, or when their import is
+-- analyzed again: revision counts their changes, and updated_at is when
+-- the latest one happened.
+ALTER TABLE deliveries ADD COLUMN revision INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE deliveries ADD COLUMN updated_at TIMESTAMP DEFAULT NULL;
+
+-- Corrections of reviewers so far.
+UPDATE deliveries
+SET revision = (SELECT COUNT(*) FROM audit_log WHERE audit_log.delivery_id = deliveries.id),
+    updated_at = approved_at
+WHERE approved_at IS NOT NULL;
@@ -11,19 +11,89 @@ ORDER BY "date" DESC;
 SELECT d.*
 FROM deliveries d
 JOIN deliveries_fts fts ON d.id = fts.id
-WHERE d.date > ?
-  AND fts.location_name MATCH ?
+WHERE d.date > sqlc.arg(date)
+  AND fts.location_name MATCH sqlc.arg(location_name)
+  AND (d.location_sectors = ''
+    OR ',' || d.location_sectors || ',' LIKE CAST(sqlc.arg(sectors) AS TEXT))
+  AND (d.location_section = ''
+    OR d.location_section LIKE CAST(sqlc.arg(section) AS TEXT))
 GROUP BY d.id
 ORDER BY d.date DESC;
 
+-- name: ListDeliveriesByLocation :many
+SELECT * FROM deliveries
+WHERE location_id = sqlc.arg(location_id)
+  AND "date" > sqlc.arg(date)
+ORDER BY "date" DESC, id DESC;
+
+-- name: FilterDeliveries :many
+SELECT * FROM deliveries
+WHERE date >= sqlc.arg(from_date)
+  AND date <= sqlc.arg(to_date)
+  AND location_type LIKE CAST(sqlc.arg(location_type) AS TEXT)
+  AND schedule_kind LIKE CAST(sqlc.arg(schedule_kind) AS TEXT)
+ORDER BY date DESC, id DESC
+LIMIT sqlc.arg(limit) OFFSET sqlc.arg(offset);
+
+-- name: FilterDeliveriesByName :many
+SELECT d.*
+FROM deliveries d
+JOIN deliveries_fts fts ON d.id = fts.id
+WHERE d.date >= sqlc.arg(from_date)
+  AND d.date <= sqlc.arg(to_date)
+  AND d.location_type LIKE CAST(sqlc.arg(location_type) AS TEXT)
+  AND d.schedule_kind LIKE CAST(sqlc.arg(schedule_kind) AS TEXT)
+  AND fts.location_name MATCH sqlc.arg(location_name)
+  AND (d.location_sectors = ''
+    OR ',' || d.location_sectors || ',' LIKE CAST(sqlc.arg(sectors) AS TEXT))
+  AND (d.location_section = ''
+    OR d.location_section LIKE CAST(sqlc.arg(section) AS TEXT))
+GROUP BY d.id
+ORDER BY d.date DESC, d.id DESC
+LIMIT sqlc.arg(limit) OFFSET sqlc.arg(offset);
+
 -- name: CreateDelivery :one
 INSERT INTO deliveries (
-  date, schedule, location_type, location_name, created_at
+  date, schedule, schedule_kind, schedule_start, schedule_end,
+  location_type, location_name, location_base, location_sectors,
+  location_section, location_orientation, notes, import_id, location_id,
+  created_at
 ) VALUES (
-  ?, ?, ?, ?, unixepoch()
+  ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, unixepoch()
 )
 RETURNING *;
 
+-- name: ListDeliveriesByImport :many
+SELECT * FROM deliveries
+WHERE import_id = ?
+ORDER BY id;
+
+-- name: UpdateDelivery :one
+UPDATE deliveries
+SET date = ?,
+    schedule = ?,
+    schedule_kind = ?,
+    schedule_start = ?,
+    schedule_end = ?,
+    location_type = ?,
+    location_name = ?,
+    location_base = ?,
+    location_sectors = ?,
+    location_section = ?,
+    location_orientation = ?,
+    notes = ?,
+    location_id = ?,
+    revision = revision + 1,
+    updated_at = unixepoch()
+WHERE id = ?
+RETURNING *;
+
+-- name: ApproveDelivery :one
+UPDATE deliveries
+SET approved_at = unixepoch()
+WHERE id = ?
+RETURNING *;
+
 -- name: DeleteDelivery :exec
 DELETE FROM deliveries
 WHERE id = ?;
@@ -31,7 +101,23 @@ WHERE id = ?;
 -- name: GetPendingImports :many
 SELECT * FROM imports
 WHERE completed_at IS NULL
-AND runs < ?
+AND dead_at IS NULL
+AND (next_attempt_at IS NULL OR next_attempt_at <= unixepoch())
+ORDER BY created_at DESC;
+
+-- name: GetImport :one
+SELECT * FROM imports
+WHERE id = ? LIMIT 1;
+
+-- name: ListImportsSince :many
+SELECT * FROM imports
+WHERE COALESCE(posted_at, created_at) >= CAST(sqlc.arg(since) AS TIMESTAMP)
+ORDER BY created_at DESC;
+
+-- name: ListFailedImports :many
+SELECT * FROM imports
+WHERE completed_at IS NULL
+AND failed_at IS NOT NULL
 ORDER BY created_at DESC;
 
 -- name: CountImportsByHash :one
@@ -40,26 +126,187 @@ WHERE file_hash = ?;
 
 -- name: CreateImport :one
 INSERT INTO imports (
-  file_path, file_hash, completed_at, runs, created_at
+  file_path, file_hash, source, post_id, post_url, post_text, posted_at,
+  completed_at, runs, created_at
 ) VALUES (
-  ?, ?, NULL, 0, unixepoch()
+  ?, ?, ?, ?, ?, ?, ?, NULL, 0, unixepoch()
 )
 RETURNING *;
 
 -- name: CompleteImport :exec
 UPDATE imports
 SET completed_at = unixepoch(),
+    next_attempt_at = NULL,
+    last_error = '',
     runs = runs + 1
 WHERE id = ?;
 
 -- name: FailImport :exec
 UPDATE imports
 SET failed_at = unixepoch(),
+    last_error = sqlc.arg(last_error),
+    next_attempt_at = sqlc.arg(next_attempt_at),
+    dead_at = sqlc.arg(dead_at),
     runs = runs + 1
+WHERE id = sqlc.arg(id);
+
+-- name: ResetImport :exec
+UPDATE imports
+SET completed_at = NULL,
+    failed_at = NULL,
+    next_attempt_at = NULL,
+    last_error = '',
+    dead_at = NULL,
+    runs = 0
 WHERE id = ?;
 
+-- name: ListDeadImports :many
+SELECT * FROM imports
+WHERE dead_at IS NOT NULL
+ORDER BY dead_at DESC, id DESC;
+
+-- name: ReviveImport :one
+UPDATE imports
+SET dead_at = NULL,
+    failed_at = NULL,
+    next_attempt_at = NULL,
+    runs = 0
+WHERE id = ?
+AND dead_at IS NOT NULL
+RETURNING *;
+
+-- name: ListImports :many
+SELECT * FROM imports
+ORDER BY created_at DESC, id DESC
+LIMIT ?;
+
 -- name: GetLatestImport :one
 SELECT * FROM imports
 WHERE completed_at IS NOT NULL
 ORDER BY created_at DESC
 LIMIT 1;
+
+-- name: CreateAnalysis :one
+INSERT INTO analyses (
+  import_id, prompt_hash, model, response, input_tokens, output_tokens,
+  latency_ms, outcome, error, created_at
+) VALUES (
+  ?, ?, ?, ?, ?, ?, ?, ?, ?, unixepoch()
+)
+RETURNING *;
+
+-- name: ListAnalysesByImport :many
+SELECT * FROM analyses
+WHERE import_id = ?
+ORDER BY created_at DESC, id DESC;
+
+-- name: QuarantineDelivery :one
+INSERT INTO quarantine (
+  import_id, date, schedule, location_type, location_name, notes, reason,
+  created_at
+) VALUES (
+  ?, ?, ?, ?, ?, ?, ?, unixepoch()
+)
+RETURNING *;
+
+-- name: GetQuarantinedDelivery :one
+SELECT * FROM quarantine
+WHERE id = ? LIMIT 1;
+
+-- name: ListQuarantine :many
+SELECT * FROM quarantine
+ORDER BY id;
+
+-- name: ListQuarantineByImport :many
+SELECT * FROM quarantine
+WHERE import_id = ?
+ORDER BY id;
+
+-- name: DeleteQuarantinedDelivery :exec
+DELETE FROM quarantine
+WHERE id = ?;
+
+-- name: DeleteQuarantineByImport :exec
+DELETE FROM quarantine
+WHERE import_id = ?;
+
+-- name: CreateAuditEntry :exec
+INSERT INTO audit_log (
+  actor, action, import_id, delivery_id, before, after, created_at
+) VALUES (
+  ?, ?, ?, ?, ?, ?, unixepoch()
+);
+
+-- name: ListAuditLogByImport :many
+SELECT * FROM audit_log
+WHERE import_id = ?
+ORDER BY id DESC;
+
+-- name: CreateLocation :one
+INSERT INTO locations (
+  slug, name, location_type, created_at
+) VALUES (
+  ?, ?, ?, unixepoch()
+)
+RETURNING *;
+
+-- name: GetLocationBySlug :one
+SELECT * FROM locations
+WHERE slug = ? LIMIT 1;
+
+-- name: ListLocations :many
+SELECT * FROM locations
+ORDER BY name;
+
+-- name: CreateLocationAlias :one
+INSERT INTO location_aliases (
+  location_id, alias, alias_key, created_at
+) VALUES (
+  ?, ?, ?, unixepoch()
+)
+RETURNING *;
+
+-- name: ListLocationAliases :many
+SELECT * FROM location_aliases
+ORDER BY id;
+
+-- name: QueueLocationReview :exec
+INSERT INTO location_reviews (
+  name, name_key, location_type, occurrences, import_id, created_at, updated_at
+) VALUES (
+  ?, ?, ?, 1, ?, unixepoch(), unixepoch()
+)
+ON CONFLICT (name_key) DO UPDATE
+SET occurrences = occurrences + 1,
+    import_id = excluded.import_id,
+    updated_at = unixepoch();
+
+-- name: ListLocationReviews :many
+SELECT * FROM location_reviews
+ORDER BY occurrences DESC, name;
+
+-- name: DeleteLocationReview :exec
+DELETE FROM location_reviews
+WHERE name_key = ?;
+
+-- name: ListUnlinkedDeliveries :many
+SELECT * FROM deliveries
+WHERE location_id IS NULL
+ORDER BY id;
+
+-- name: ListAllDeliveries :many
+SELECT * FROM deliveries
+ORDER BY id;
+
+-- name: SetDeliveryNameParts :exec
+UPDATE deliveries
+SET location_base = ?,
+    location_sectors = ?,
+    location_section = ?,
+    location_orientation = ?
+WHERE id = ?;
+
+-- name: SetDeliveryLocation :exec
+UPDATE deliveries
+SET location_id = ?
+WHERE id = ?;
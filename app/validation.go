@@ -0,0 +1,77 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"fmt"
+	"time"
+
+	"git.cypr.io/oz/aguaxaca/app/db"
+	"git.cypr.io/oz/aguaxaca/gazetteer"
+)
+
+// MaxPostDateDrift is the number of days between a notice's publication
+// and a delivery date, after which the extracted date looks suspicious.
+const MaxPostDateDrift = 7
+
+// MaxDeliveriesPerImport is the number of deliveries found in a single
+// image, after which the extraction looks suspicious.
+const MaxDeliveriesPerImport = 100
+
+// Validator checks deliveries extracted by the parser before they are
+// stored. Suspicious deliveries are quarantined.
+type Validator struct {
+	MaxPostDateDrift int // days
+	MaxDeliveries    int // per import
+}
+
+func NewValidator() *Validator {
+	return &Validator{
+		MaxPostDateDrift: MaxPostDateDrift,
+		MaxDeliveries:    MaxDeliveriesPerImport,
+	}
+}
+
+// Check returns why a delivery extracted from im is suspicious, or an
+// empty string. count is the number of deliveries found in im.
+func (v *Validator) Check(im *db.Import, count int, c *candidate) string {
+	if count > v.MaxDeliveries {
+		return fmt.Sprintf("too many deliveries in one image (%d > %d)", count, v.MaxDeliveries)
+	}
+
+	if gazetteer.Key(c.LocationName) == "" {
+		return "empty location name"
+	}
+
+	if !c.schedule.Known() {
+		return fmt.Sprintf("unknown schedule '%s'", c.Schedule)
+	}
+
+	// Dates are only checked when the notice's publication date is known.
+	if im.PostedAt != nil {
+		drift := c.date.Sub(im.PostedAt.Time)
+		if drift < 0 {
+			drift = -drift
+		}
+		if drift > time.Duration(v.MaxPostDateDrift)*24*time.Hour {
+			return fmt.Sprintf("date %s is more than %d days from the post date %s",
+				c.date.Format(DateFormat), v.MaxPostDateDrift, im.PostedAt.Time.Format(DateFormat))
+		}
+	}
+
+	return ""
+}
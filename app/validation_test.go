@@ -0,0 +1,114 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"git.cypr.io/oz/aguaxaca/app/db"
+	"git.cypr.io/oz/aguaxaca/parser"
+)
+
+func TestValidatorCheck(t *testing.T) {
+	posted := time.Date(2025, 7, 14, 0, 0, 0, 0, time.UTC)
+	delivery := func(date, schedule, name string) parser.Delivery {
+		return parser.Delivery{Date: date, Schedule: schedule, LocationType: "colonia", LocationName: name}
+	}
+
+	tests := []struct {
+		name     string
+		postedAt *time.Time
+		count    int
+		delivery parser.Delivery
+		want     string // prefix of the reason, empty when valid
+	}{
+		{"valid", &posted, 1, delivery("2025-07-15", "matutino", "Libertad"), ""},
+
+		// Post date drift, in days: at most 7 days before, or after.
+		{"same day", &posted, 1, delivery("2025-07-14", "matutino", "Libertad"), ""},
+		{"7 days after", &posted, 1, delivery("2025-07-21", "matutino", "Libertad"), ""},
+		{"8 days after", &posted, 1, delivery("2025-07-22", "matutino", "Libertad"), "date 2025-07-22 is more than 7 days"},
+		{"7 days before", &posted, 1, delivery("2025-07-07", "matutino", "Libertad"), ""},
+		{"8 days before", &posted, 1, delivery("2025-07-06", "matutino", "Libertad"), "date 2025-07-06 is more than 7 days"},
+		{"wrong year", &posted, 1, delivery("2024-07-15", "matutino", "Libertad"), "date 2024-07-15 is more than 7 days"},
+		{"unknown post date", nil, 1, delivery("2024-07-15", "matutino", "Libertad"), ""},
+
+		// Deliveries per import: at most 100.
+		{"100 deliveries", &posted, 100, delivery("2025-07-15", "matutino", "Libertad"), ""},
+		{"101 deliveries", &posted, 101, delivery("2025-07-15", "matutino", "Libertad"), "too many deliveries in one image (101 > 100)"},
+
+		// Names, and schedules.
+		{"empty name", &posted, 1, delivery("2025-07-15", "matutino", "Colonia de la"), "empty location name"},
+		{"unknown schedule", &posted, 1, delivery("2025-07-15", "madrugada", "Libertad"), "unknown schedule 'madrugada'"},
+		{"explicit times", &posted, 1, delivery("2025-07-15", "de 6 a 14 hrs", "Libertad"), ""},
+	}
+
+	v := NewValidator()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			im := &db.Import{}
+			if tt.postedAt != nil {
+				im.PostedAt = &db.UnixTime{Time: *tt.postedAt}
+			}
+			c, err := newCandidate(tt.delivery)
+			if err != nil {
+				t.Fatalf("newCandidate(%#v): %v", tt.delivery, err)
+			}
+
+			got := v.Check(im, tt.count, c)
+			if (tt.want == "" && got != "") || !strings.HasPrefix(got, tt.want) {
+				t.Errorf("Check() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCandidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		delivery parser.Delivery
+		wantType string
+		wantErr  string
+	}{
+		{"colonia", parser.Delivery{Date: "2025-07-15", LocationType: "colonia"}, "colonia", ""},
+		{"type alias", parser.Delivery{Date: "2025-07-15", LocationType: "Fracc."}, "fraccionamiento", ""},
+		{"plural type", parser.Delivery{Date: "2025-07-15", LocationType: "Colonias"}, "colonia", ""},
+		{"unknown type", parser.Delivery{Date: "2025-07-15", LocationType: "ciudad"}, "", "unknown location type 'ciudad'"},
+		{"empty type", parser.Delivery{Date: "2025-07-15"}, "", "unknown location type ''"},
+		{"invalid date", parser.Delivery{Date: "15/07/2025", LocationType: "colonia"}, "", "invalid date format '15/07/2025'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := newCandidate(tt.delivery)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("newCandidate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newCandidate(): %v", err)
+			}
+			if c.locationType != tt.wantType {
+				t.Errorf("newCandidate() type = %q, want %q", c.locationType, tt.wantType)
+			}
+		})
+	}
+}
@@ -16,8 +16,27 @@
 
 package collector
 
+import "time"
+
+// Item is a collected post (a tweet, usually), with the paths of the
+// images that were downloaded from it.
+type Item struct {
+	Source   string    // where the post was collected, eg. "nitter"
+	PostID   string    // ID of the post on its network
+	URL      string    // public permalink to the post
+	PostedAt time.Time // publication time, zero when unknown
+	Text     string    // text content of the post
+	Images   []string  // paths of downloaded images
+}
+
 // Collector is a basic interface for types that can download images to
 // be imported.
 type Collector interface {
-	DownloadImages() ([]string, error)
+	DownloadImages() ([]Item, error)
+}
+
+// Backfiller is implemented by collectors that can also fetch older
+// posts, published after since, reading at most maxPages pages.
+type Backfiller interface {
+	Backfill(since time.Time, maxPages int) ([]Item, error)
 }
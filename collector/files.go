@@ -0,0 +1,99 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package collector
+
+import (
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ManualSource is the default Item.Source of explicitly imported files.
+const ManualSource = "manual"
+
+// FilesCollector copies a list of image files, or directories of
+// images, to the download directory. It's used to import archived
+// notices, with metadata given by the operator.
+type FilesCollector struct {
+	Paths       []string
+	DownloadDir string
+	Source      string
+	URL         string
+	PostedAt    time.Time
+	Log         *slog.Logger
+}
+
+// NewFilesCollector builds a collector for the images in paths.
+func NewFilesCollector(paths []string) *FilesCollector {
+	return &FilesCollector{
+		Paths:       paths,
+		DownloadDir: DefaultDownloadDir,
+		Source:      ManualSource,
+		Log:         slog.Default(),
+	}
+}
+
+// DownloadImages copies accepted images to the download directory, and
+// returns one item per image. Directories are walked recursively.
+func (fc *FilesCollector) DownloadImages() ([]Item, error) {
+	items := []Item{}
+
+	if err := os.MkdirAll(fc.DownloadDir, 0o755); err != nil {
+		return nil, err
+	}
+
+	for _, root := range fc.Paths {
+		err := filepath.WalkDir(root, func(src string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+
+			if err := CheckImage(src); err != nil {
+				// Report files given explicitly, not those found in directories.
+				if src == root {
+					fc.Log.Warn("skipped file", "path", src, "error", err)
+				}
+				return nil
+			}
+
+			dest := destPath(src, fc.DownloadDir)
+			if err := copyFile(src, dest); err != nil {
+				fc.Log.Error("can't copy file", "path", src, "error", err)
+				return nil
+			}
+			fc.Log.Info("found image", "path", src, "dest", dest)
+
+			items = append(items, Item{
+				Source:   fc.Source,
+				URL:      fc.URL,
+				PostedAt: fc.PostedAt,
+				Images:   []string{dest},
+			})
+			return nil
+		})
+		if err != nil {
+			return items, err
+		}
+	}
+
+	return items, nil
+}
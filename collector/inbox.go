@@ -0,0 +1,198 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package collector
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultInboxDir is where images are dropped manually.
+const DefaultInboxDir = "./inbox"
+
+// InboxSource is the Item.Source of images found in the inbox.
+const InboxSource = "inbox"
+
+// ImageTypes are the accepted image formats, by file extension.
+var ImageTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".webp": "image/webp",
+}
+
+// InboxCollector picks images from a local directory, where notices
+// that were not published on X (sent by email, WhatsApp, ...) can be
+// dropped manually.
+type InboxCollector struct {
+	InboxDir    string
+	DownloadDir string
+	Log         *slog.Logger
+}
+
+// NewInboxCollector builds a collector for images in inboxDir.
+func NewInboxCollector(inboxDir string) *InboxCollector {
+	return &InboxCollector{
+		InboxDir:    inboxDir,
+		DownloadDir: DefaultDownloadDir,
+		Log:         slog.Default(),
+	}
+}
+
+// DownloadImages moves accepted images from the inbox to the download
+// directory, and returns one item per image. Other files are left in
+// the inbox.
+func (ic *InboxCollector) DownloadImages() ([]Item, error) {
+	items := []Item{}
+
+	if err := os.MkdirAll(ic.DownloadDir, 0o755); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(ic.InboxDir)
+	if err != nil {
+		return nil, fmt.Errorf("can't read inbox %s: %w", ic.InboxDir, err)
+	}
+
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		src := filepath.Join(ic.InboxDir, entry.Name())
+
+		if err := CheckImage(src); err != nil {
+			ic.Log.Warn("skipped inbox file", "path", src, "error", err)
+			continue
+		}
+
+		dest, err := moveFile(src, ic.DownloadDir)
+		if err != nil {
+			ic.Log.Error("can't move inbox file", "path", src, "error", err)
+			continue
+		}
+		ic.Log.Info("found image", "path", src, "dest", dest)
+
+		items = append(items, Item{
+			Source: InboxSource,
+			Images: []string{dest},
+		})
+	}
+
+	return items, nil
+}
+
+// CheckImage returns an error if the file at path isn't an accepted
+// image format: both its extension and its content must match.
+func CheckImage(path string) error {
+	ext := strings.ToLower(filepath.Ext(path))
+	mimeType, ok := ImageTypes[ext]
+	if !ok {
+		return fmt.Errorf("unsupported file extension '%s'", ext)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// DetectContentType reads at most 512 bytes.
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	if detected := http.DetectContentType(head[:n]); detected != mimeType {
+		return fmt.Errorf("content type '%s' does not match extension '%s'", detected, ext)
+	}
+	return nil
+}
+
+// moveFile moves src into the dir directory, without overwriting
+// other files, and returns the new path.
+func moveFile(src string, dir string) (string, error) {
+	dest := destPath(src, dir)
+
+	// Rename fails across file systems: copy, and remove instead.
+	if err := os.Rename(src, dest); err == nil {
+		return dest, nil
+	}
+	if err := copyFile(src, dest); err != nil {
+		return "", err
+	}
+	return dest, os.Remove(src)
+}
+
+// destPath finds where src should be copied in the dir directory: a
+// path with the same file name, unless there's already a different
+// file there.
+func destPath(src string, dir string) string {
+	name := filepath.Base(src)
+	ext := filepath.Ext(name)
+	dest := path.Join(dir, name)
+	for i := 1; fileExists(dest) && !sameContent(src, dest); i++ {
+		dest = path.Join(dir, fmt.Sprintf("%s-%d%s", strings.TrimSuffix(name, ext), i, ext))
+	}
+	return dest
+}
+
+func copyFile(src string, dest string) error {
+	if sameContent(src, dest) {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0640)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dest)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dest)
+		return err
+	}
+	return nil
+}
+
+// sameContent is true when both files exist, and are identical.
+func sameContent(a string, b string) bool {
+	contentA, err := os.ReadFile(a)
+	if err != nil {
+		return false
+	}
+	contentB, err := os.ReadFile(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(contentA, contentB)
+}
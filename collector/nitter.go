@@ -20,9 +20,11 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/gocolly/colly/v2"
 )
@@ -33,6 +35,19 @@ const DefaultBaseDomain = "https://nitter.net"
 // defaultDownloadDir is where images will be saved.
 const DefaultDownloadDir = "./images"
 
+// DefaultCacheDir is where colly caches HTTP responses.
+const DefaultCacheDir = "./cache"
+
+// DefaultPageDelay is the pause between two timeline pages, when
+// backfilling older posts.
+const DefaultPageDelay = 5 * time.Second
+
+// NitterSource is the Item.Source of posts found on Nitter.
+const NitterSource = "nitter"
+
+// Format of the tweet dates' title attribute on Nitter timelines.
+const nitterDateFormat = "Jan 2, 2006 · 3:04 PM MST"
+
 type fileExistsError struct {
 	name string
 }
@@ -44,7 +59,9 @@ func (e *fileExistsError) Error() string {
 type NitterCollector struct {
 	BaseDomain  string
 	DownloadDir string
+	CacheDir    string
 	Account     string
+	PageDelay   time.Duration
 	Log         *slog.Logger
 }
 
@@ -58,23 +75,42 @@ func NewNitterCollector(account string) *NitterCollector {
 		Account:     account,
 		BaseDomain:  DefaultBaseDomain,
 		DownloadDir: DefaultDownloadDir,
+		CacheDir:    DefaultCacheDir,
+		PageDelay:   DefaultPageDelay,
 		Log:         slog.Default(),
 	}
 }
 
 // DownloadImages scrapes images from a Nitter HTML timeline, and
-// returns a list of paths when images where downloaded.
-func (nc *NitterCollector) DownloadImages() ([]string, error) {
+// returns the posts where images where downloaded.
+func (nc *NitterCollector) DownloadImages() ([]Item, error) {
+	return nc.scrape(time.Time{}, 1)
+}
+
+// Backfill scrapes older timeline pages, following Nitter's "Load more"
+// links until it reaches posts published before since, or maxPages
+// pages were visited. It waits PageDelay between two pages.
+func (nc *NitterCollector) Backfill(since time.Time, maxPages int) ([]Item, error) {
+	return nc.scrape(since, maxPages)
+}
+
+// scrape visits up to maxPages timeline pages, and stops after the
+// first page with posts older than since (when it's not zero).
+func (nc *NitterCollector) scrape(since time.Time, maxPages int) ([]Item, error) {
 	c := nc.getFirefoxCollector()
-	files := []string{}
+	items := []Item{}
 
-	if err := os.Mkdir(nc.DownloadDir, 0750); err != nil && !os.IsExist(err) {
+	if err := os.MkdirAll(nc.DownloadDir, 0o755); err != nil {
 		return nil, err
 	}
 
 	// Collect all scraping errors
 	var err error = nil
 
+	// Oldest post, and "Load more" link on the current page.
+	var oldest time.Time
+	nextURL := ""
+
 	c.OnHTML("title", func(e *colly.HTMLElement) {
 		if strings.Index(e.Text, "Maintenance") == 0 {
 			err = errors.Join(fmt.Errorf("server unavailable (maintenance)"))
@@ -83,6 +119,18 @@ func (nc *NitterCollector) DownloadImages() ([]string, error) {
 
 	// Lookup timeline items
 	c.OnHTML(".timeline-item", func(e *colly.HTMLElement) {
+		item := nc.newItem(e)
+
+		// Pinned tweets are out of order, and don't tell how far back
+		// in the timeline we are.
+		pinned := e.DOM.Find(".pinned").Length() > 0
+		if !pinned && !item.PostedAt.IsZero() && (oldest.IsZero() || item.PostedAt.Before(oldest)) {
+			oldest = item.PostedAt
+		}
+		if !since.IsZero() && !item.PostedAt.IsZero() && item.PostedAt.Before(since) {
+			return
+		}
+
 		txt := e.ChildText("*")
 		// Ignore tweets that don't contain the correct hashtag.
 		if !strings.Contains(txt, "HoyLlegaElAgua") {
@@ -91,7 +139,7 @@ func (nc *NitterCollector) DownloadImages() ([]string, error) {
 
 		e.ForEach(".attachments a.still-image", func(i int, a *colly.HTMLElement) {
 			imgURL := nc.BaseDomain + a.Attr("href")
-			nc.Log.Info("found image", "url", imgURL)
+			nc.Log.Info("found image", "url", imgURL, "post", item.PostID)
 
 			file, err := nc.downloadImage(imgURL)
 			if err != nil {
@@ -103,8 +151,18 @@ func (nc *NitterCollector) DownloadImages() ([]string, error) {
 				nc.Log.Error("download error", "url", imgURL, "error", err)
 				return
 			}
-			files = append(files, file)
+			item.Images = append(item.Images, file)
 		})
+
+		if len(item.Images) > 0 {
+			items = append(items, item)
+		}
+	})
+
+	// The bottom "Load more" link holds the cursor to the next page (the
+	// top one, "Load newest", is also a .timeline-item).
+	c.OnHTML(".show-more:not(.timeline-item) a", func(e *colly.HTMLElement) {
+		nextURL = nc.BaseDomain + "/" + nc.Account + e.Attr("href")
 	})
 
 	// Set error handler
@@ -112,12 +170,49 @@ func (nc *NitterCollector) DownloadImages() ([]string, error) {
 		nc.Log.Error("HTTP error", "status", r.StatusCode, "url", r.Request.URL, "error", err)
 	})
 
-	c.Visit(nc.BaseDomain + "/" + nc.Account)
-	nc.Log.Debug("waiting for pending requests")
-	c.Wait()
+	pageURL := nc.BaseDomain + "/" + nc.Account
+	for page := 1; page <= maxPages && pageURL != ""; page++ {
+		if page > 1 {
+			time.Sleep(nc.PageDelay)
+		}
+		oldest, nextURL = time.Time{}, ""
+
+		nc.Log.Info("scraping timeline", "page", page, "url", pageURL)
+		c.Visit(pageURL)
+		nc.Log.Debug("waiting for pending requests")
+		c.Wait()
+
+		if !since.IsZero() && !oldest.IsZero() && oldest.Before(since) {
+			nc.Log.Info("reached posts older than limit", "since", since, "oldest", oldest)
+			break
+		}
+		pageURL = nextURL
+	}
 	nc.Log.Info("finished scraping")
 
-	return files, err
+	return items, err
+}
+
+// newItem reads a tweet's metadata from a .timeline-item element.
+func (nc *NitterCollector) newItem(e *colly.HTMLElement) Item {
+	item := Item{
+		Source: NitterSource,
+		Text:   strings.TrimSpace(e.ChildText(".tweet-content")),
+	}
+
+	if account, id, ok := parseStatusPath(e.ChildAttr("a.tweet-link", "href")); ok {
+		item.PostID = id
+		item.URL = statusURL(account, id)
+	}
+
+	dateTitle := e.ChildAttr(".tweet-date a", "title")
+	if postedAt, err := time.Parse(nitterDateFormat, dateTitle); err == nil {
+		item.PostedAt = postedAt.UTC()
+	} else if dateTitle != "" {
+		nc.Log.Debug("invalid tweet date", "date", dateTitle, "error", err)
+	}
+
+	return item
 }
 
 // Try to download image once.
@@ -157,7 +252,7 @@ func (nc *NitterCollector) getFirefoxCollector() *colly.Collector {
 		colly.IgnoreRobotsTxt(),
 
 		// TODO: CacheExpiration isn't automatic in colly v2.
-		colly.CacheDir("cache"),
+		colly.CacheDir(nc.CacheDir),
 	)
 	c.OnRequest(func(r *colly.Request) {
 		nc.Log.Debug("GET", "url", r.URL)
@@ -174,6 +269,25 @@ func (nc *NitterCollector) getFirefoxCollector() *colly.Collector {
 	return c
 }
 
+// parseStatusPath splits a Nitter status link ("/account/status/123#m")
+// into an account name, and a tweet ID.
+func parseStatusPath(link string) (string, string, bool) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", "", false
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 3 || parts[1] != "status" {
+		return "", "", false
+	}
+	return parts[0], parts[2], true
+}
+
+// statusURL is the public permalink to a tweet.
+func statusURL(account, id string) string {
+	return "https://x.com/" + account + "/status/" + id
+}
+
 func fileExists(filename string) bool {
 	info, err := os.Stat(filename)
 	if os.IsNotExist(err) {
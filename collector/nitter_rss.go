@@ -0,0 +1,176 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package collector
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// Image tags in the HTML description of Nitter's RSS items.
+var rssImageRe = regexp.MustCompile(`<img[^>]+src="([^"]+)"`)
+
+// Any HTML tag, stripped from descriptions to get a post's text.
+var rssTagRe = regexp.MustCompile(`<[^>]*>`)
+
+type rssFeed struct {
+	Items []rssItem `xml:"channel>item"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Description string `xml:"description"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	PubDate     string `xml:"pubDate"`
+}
+
+// NitterRSSCollector reads a Nitter account's RSS feed instead of
+// scraping its HTML timeline. RSS must be enabled on the instance.
+type NitterRSSCollector struct {
+	NitterCollector
+}
+
+// NewNitterRSSCollector builds a Nitter RSS collector with the same
+// defaults as NewNitterCollector.
+func NewNitterRSSCollector(account string) *NitterRSSCollector {
+	return &NitterRSSCollector{NitterCollector: *NewNitterCollector(account)}
+}
+
+// DownloadImages fetches the account's RSS feed, and returns the posts
+// where images where downloaded.
+func (nc *NitterRSSCollector) DownloadImages() ([]Item, error) {
+	c := nc.getFirefoxCollector()
+	items := []Item{}
+
+	if err := os.MkdirAll(nc.DownloadDir, 0o755); err != nil {
+		return nil, err
+	}
+
+	var err error = nil
+
+	c.OnRequest(func(r *colly.Request) {
+		r.Headers.Set("Accept", "application/rss+xml,application/xml;q=0.9,*/*;q=0.8")
+	})
+
+	c.OnResponse(func(r *colly.Response) {
+		var feed rssFeed
+		if xmlErr := xml.Unmarshal(r.Body, &feed); xmlErr != nil {
+			err = errors.Join(err, fmt.Errorf("invalid RSS feed: %w", xmlErr))
+			return
+		}
+
+		for _, item := range feed.Items {
+			// Ignore tweets that don't contain the correct hashtag.
+			if !strings.Contains(item.Title+item.Description, "HoyLlegaElAgua") {
+				continue
+			}
+
+			post := nc.newItem(&item)
+			for _, imgURL := range nc.imageURLs(item.Description) {
+				nc.Log.Info("found image", "url", imgURL, "post", post.PostID)
+
+				file, dlErr := nc.downloadImage(imgURL)
+				if dlErr != nil {
+					// Silently ignore files that were already downloaded.
+					var fileExistsErr *fileExistsError
+					if errors.As(dlErr, &fileExistsErr) {
+						continue
+					}
+					nc.Log.Error("download error", "url", imgURL, "error", dlErr)
+					continue
+				}
+				post.Images = append(post.Images, file)
+			}
+
+			if len(post.Images) > 0 {
+				items = append(items, post)
+			}
+		}
+	})
+
+	c.OnError(func(r *colly.Response, reqErr error) {
+		nc.Log.Error("HTTP error", "status", r.StatusCode, "url", r.Request.URL, "error", reqErr)
+		err = errors.Join(err, reqErr)
+	})
+
+	c.Visit(nc.BaseDomain + "/" + nc.Account + "/rss")
+	nc.Log.Debug("waiting for pending requests")
+	c.Wait()
+	nc.Log.Info("finished reading RSS feed")
+
+	return items, err
+}
+
+// newItem reads a tweet's metadata from an RSS item.
+func (nc *NitterRSSCollector) newItem(item *rssItem) Item {
+	post := Item{
+		Source: NitterSource,
+		Text:   html.UnescapeString(rssTagRe.ReplaceAllString(item.Description, " ")),
+	}
+	post.Text = strings.Join(strings.Fields(post.Text), " ")
+
+	if account, id, ok := parseStatusPath(item.Link); ok {
+		post.PostID = id
+		post.URL = statusURL(account, id)
+	}
+
+	if postedAt, err := time.Parse(time.RFC1123, item.PubDate); err == nil {
+		post.PostedAt = postedAt.UTC()
+	} else if item.PubDate != "" {
+		nc.Log.Debug("invalid RSS date", "date", item.PubDate, "error", err)
+	}
+
+	return post
+}
+
+// imageURLs extracts full-size image URLs from an RSS item description.
+//
+// Nitter builds absolute URLs with its own configured hostname, which
+// may not be reachable from here (private instances), so only the path
+// is kept, and rebased on BaseDomain. Thumbnails ("/pic/media%2F...")
+// are swapped for originals ("/pic/orig/media%2F..."), like the links
+// found on the HTML timeline.
+func (nc *NitterRSSCollector) imageURLs(description string) []string {
+	urls := []string{}
+	for _, match := range rssImageRe.FindAllStringSubmatch(description, -1) {
+		u, err := url.Parse(match[1])
+		if err != nil {
+			nc.Log.Debug("invalid image URL", "url", match[1], "error", err)
+			continue
+		}
+
+		p := u.EscapedPath()
+		if !strings.HasPrefix(p, "/pic/") {
+			continue
+		}
+		if !strings.HasPrefix(p, "/pic/orig/") {
+			p = "/pic/orig/" + strings.TrimPrefix(p, "/pic/")
+		}
+		urls = append(urls, nc.BaseDomain+p)
+	}
+	return urls
+}
@@ -0,0 +1,157 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package gazetteer resolves location names, as written in public
+// notices, to canonical locations.
+package gazetteer
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// stopWords are ignored when comparing names: articles, and generic
+// words that notices add, or omit, at random.
+var stopWords = map[string]bool{
+	"de": true, "del": true, "el": true, "la": true, "las": true, "los": true, "y": true,
+	"colonia": true, "sector": true, "seccion": true,
+}
+
+// Fold removes accents, punctuation, and case from s: "Jardín (sector
+// Bugambilias)" becomes "jardin sector bugambilias".
+func Fold(s string) string {
+	var b strings.Builder
+	space := false
+	// NFKD splits accents from letters, and turns "ª" into "a".
+	for _, r := range norm.NFKD.String(s) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			space = false
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// Key is the folded form of a name, without stop words, used to match
+// aliases.
+func Key(name string) string {
+	words := []string{}
+	for _, word := range strings.Fields(Fold(name)) {
+		if !stopWords[word] {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// Slug is a URL-friendly version of a name: "Jardín (sector
+// Bugambilias)" becomes "jardin-sector-bugambilias".
+func Slug(name string) string {
+	return strings.ReplaceAll(Fold(name), " ", "-")
+}
+
+// Distance is the Levenshtein distance between a and b, in runes.
+func Distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// Matcher finds the location of a name among known aliases.
+type Matcher struct {
+	aliases map[string]int64 // location ID, by alias key
+}
+
+// NewMatcher builds an empty matcher.
+func NewMatcher() *Matcher {
+	return &Matcher{aliases: map[string]int64{}}
+}
+
+// Add an alias of a location.
+func (m *Matcher) Add(locationID int64, alias string) {
+	m.aliases[Key(alias)] = locationID
+}
+
+// Match returns the ID of the location with an alias matching name:
+// either the same key, or a key within a small edit distance (about one
+// typo per 8 characters). Fuzzy matches must have the same numbers, so
+// that "sector 1" never matches "sector 2", and must be unambiguous.
+func (m *Matcher) Match(name string) (int64, bool) {
+	key := Key(name)
+	if key == "" {
+		return 0, false
+	}
+	if id, ok := m.aliases[key]; ok {
+		return id, true
+	}
+
+	maxDistance := len([]rune(key)) / 8
+	if maxDistance == 0 {
+		return 0, false
+	}
+
+	digits := onlyDigits(key)
+	var bestID int64
+	bestDistance := maxDistance + 1
+	ambiguous := false
+	for alias, id := range m.aliases {
+		if onlyDigits(alias) != digits {
+			continue
+		}
+		d := Distance(key, alias)
+		switch {
+		case d < bestDistance:
+			bestID, bestDistance, ambiguous = id, d, false
+		case d == bestDistance && id != bestID:
+			ambiguous = true
+		}
+	}
+	if bestDistance > maxDistance || ambiguous {
+		return 0, false
+	}
+	return bestID, true
+}
+
+// onlyDigits returns the numbers in s: "sector 1 2a" becomes "1 2".
+func onlyDigits(s string) string {
+	return strings.Join(strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsDigit(r) }), " ")
+}
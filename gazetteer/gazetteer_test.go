@@ -0,0 +1,167 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package gazetteer
+
+import "testing"
+
+func TestFold(t *testing.T) {
+	tests := []struct{ name, want string }{
+		{"Libertad", "libertad"},
+		{"  San Martín   Mexicapam ", "san martin mexicapam"},
+		{"2ª Sección", "2a seccion"},
+		{"Niños Héroes (sector 1; sector 2)", "ninos heroes sector 1 sector 2"},
+		{"Ex-Hacienda Candiani", "ex hacienda candiani"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Fold(tt.name); got != tt.want {
+			t.Errorf("Fold(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct{ name, want string }{
+		{"Colonia Libertad", "libertad"},
+		{"Jardines de Las Lomas", "jardines lomas"},
+		{"Guadalupe Victoria Sector 1", "guadalupe victoria 1"},
+		{"Guadalupe Victoria (sector 1)", "guadalupe victoria 1"},
+		{"de la", ""},
+	}
+	for _, tt := range tests {
+		if got := Key(tt.name); got != tt.want {
+			t.Errorf("Key(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := []struct{ name, want string }{
+		{"Libertad", "libertad"},
+		{"San Martín Mexicapam", "san-martin-mexicapam"},
+		{"Guadalupe Victoria (sector 1, 2ª sección)", "guadalupe-victoria-sector-1-2a-seccion"},
+		{"5 Señores", "5-senores"},
+	}
+	for _, tt := range tests {
+		if got := Slug(tt.name); got != tt.want {
+			t.Errorf("Slug(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"libertad", "libertad", 0},
+		{"libertad", "", 8},
+		{"libertad", "libertat", 1},
+		{"libertad", "liberad", 1},
+		{"libertad", "libertadd", 1},
+		{"reforma", "forma", 2},
+		{"ñu", "nu", 1},
+	}
+	for _, tt := range tests {
+		if got := Distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("Distance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	m := NewMatcher()
+	m.Add(1, "Libertad")
+	m.Add(2, "Jardines de Las Lomas")
+	m.Add(3, "Guadalupe Victoria Sector 1")
+	m.Add(4, "Guadalupe Victoria Sector 2")
+	m.Add(5, "Centro")
+	m.Add(6, "San Juan Chapultepec")
+	m.Add(7, "San Juan Chapultepeq")
+	m.Add(1, "Colonia La Libertad")
+
+	tests := []struct {
+		name   string
+		wantID int64
+		wantOK bool
+	}{
+		// Exact keys: case, accents, punctuation, and stop words don't
+		// matter.
+		{"Libertad", 1, true},
+		{"colonia LIBERTAD", 1, true},
+		{"La Libertad", 1, true},
+		{"Jardínes de las Lomas", 2, true},
+		{"Guadalupe Victoria (sector 2)", 4, true},
+		{"Centro", 5, true},
+
+		// Typos, up to one edit per 8 characters.
+		{"Libertat", 1, true},
+		{"Jardines de las Lomaz", 2, true},
+		{"Jardin de las Lomas", 0, false},
+		{"Guadalupe Vitoria sector 1", 3, true},
+		{"Guadalupe Vitoria sector 3", 0, false},
+
+		// Short names must match exactly.
+		{"Centra", 0, false},
+
+		// Numbers must be the same: sector 1 is not sector 2.
+		{"Guadalupe Victoria Sector 12", 0, false},
+		{"Guadalupe Victoria", 0, false},
+
+		// Ambiguous matches are refused.
+		{"San Juan Chapultepex", 0, false},
+		{"San Juan Chapultepec", 6, true},
+
+		// Nothing to match.
+		{"", 0, false},
+		{"de la", 0, false},
+		{"Reforma", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, ok := m.Match(tt.name)
+			if id != tt.wantID || ok != tt.wantOK {
+				t.Errorf("Match(%q) = %d, %t, want %d, %t", tt.name, id, ok, tt.wantID, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestLocationType(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   string
+		wantOK bool
+	}{
+		{"colonia", "colonia", true},
+		{"Colonias", "colonia", true},
+		{"Fracc.", "fraccionamiento", true},
+		{"fraccionamientos", "fraccionamiento", true},
+		{"U.H.", "unidad", true},
+		{"Unidades", "unidad", true},
+		{"Rancherías", "ranchería", true},
+		{"Barrios", "barrio", true},
+		{"ciudad", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := LocationType(tt.name)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("LocationType(%q) = %q, %t, want %q, %t", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
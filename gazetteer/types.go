@@ -0,0 +1,99 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package gazetteer
+
+import "strings"
+
+// LocationTypes is the closed vocabulary of location types.
+var LocationTypes = []string{
+	"colonia",
+	"fraccionamiento",
+	"unidad",
+	"ejido",
+	"barrio",
+	"agencia",
+	"paraje",
+	"privada",
+	"residencial",
+	"condominio",
+	"conjunto",
+	"ranchería",
+	"localidad",
+	"calle",
+}
+
+// TypeAliases maps other names of location types, folded and singular,
+// to their canonical form.
+var TypeAliases = map[string]string{
+	"col":                   "colonia",
+	"fracc":                 "fraccionamiento",
+	"fracto":                "fraccionamiento",
+	"frac":                  "fraccionamiento",
+	"unidad habitacional":   "unidad",
+	"u h":                   "unidad",
+	"uh":                    "unidad",
+	"agencia municipal":     "agencia",
+	"agencia de policia":    "agencia",
+	"conjunto habitacional": "conjunto",
+	"conjunto residencial":  "conjunto",
+	"cerrada":               "privada",
+	"rancheria":             "ranchería",
+	"av":                    "calle",
+	"avenida":               "calle",
+	"andador":               "calle",
+}
+
+// typesByFold maps folded location types to their canonical form.
+var typesByFold = map[string]string{}
+
+func init() {
+	for _, t := range LocationTypes {
+		typesByFold[Fold(t)] = t
+	}
+	for alias, t := range TypeAliases {
+		typesByFold[alias] = t
+	}
+}
+
+// LocationType returns the canonical form of a location type, or false
+// if it's not part of the vocabulary: "Colonias", and "col." are both
+// "colonia".
+func LocationType(s string) (string, bool) {
+	folded := Fold(s)
+	for _, candidate := range []string{folded, singular(folded, true), singular(folded, false)} {
+		if t, ok := typesByFold[candidate]; ok {
+			return t, true
+		}
+	}
+	return "", false
+}
+
+// singular returns the singular form of each word in s. Plurals in "es"
+// are only recognized after consonants when consonantES is true, and
+// ignored otherwise: "unidades" is "unidad", but "calles" is "calle".
+func singular(s string, consonantES bool) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		switch {
+		case consonantES && len(w) > 3 && strings.HasSuffix(w, "es") && strings.ContainsAny(w[len(w)-3:len(w)-2], "dlnrz"):
+			words[i] = w[:len(w)-2]
+		case len(w) > 2 && strings.HasSuffix(w, "s"):
+			words[i] = w[:len(w)-1]
+		}
+	}
+	return strings.Join(words, " ")
+}
@@ -10,6 +10,7 @@ require (
 	github.com/go-co-op/gocron/v2 v2.16.5
 	github.com/gocolly/colly/v2 v2.2.0
 	github.com/peterbourgon/ff/v3 v3.4.0
+	golang.org/x/text v0.27.0
 	modernc.org/sqlite v1.38.0
 )
 
@@ -41,7 +42,6 @@ require (
 	golang.org/x/exp v0.0.0-20250408133849-7e4ce0ab07d0 // indirect
 	golang.org/x/net v0.42.0 // indirect
 	golang.org/x/sys v0.34.0 // indirect
-	golang.org/x/text v0.27.0 // indirect
 	google.golang.org/appengine v1.6.8 // indirect
 	google.golang.org/protobuf v1.36.6 // indirect
 	modernc.org/libc v1.65.10 // indirect
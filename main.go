@@ -21,16 +21,29 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
-	"git.cypr.io/oz/aguaxaca/app"
+	appPkg "git.cypr.io/oz/aguaxaca/app"
+	"git.cypr.io/oz/aguaxaca/collector"
+	"git.cypr.io/oz/aguaxaca/parser"
 	"git.cypr.io/oz/aguaxaca/web"
 	"git.cypr.io/oz/aguaxaca/workers"
 	"github.com/peterbourgon/ff/v3/ffcli"
 )
 
 func main() {
-	ctx := context.Background()
-	app := app.NewApp(ctx)
+	// Ctrl-C cancels the context: long commands, like analyze, stop
+	// cleanly. A second Ctrl-C kills the program.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	app := appPkg.NewApp(ctx)
 
 	// CLI command: aguaxaca collect
 	collectCmd := &ffcli.Command{
@@ -46,21 +59,396 @@ func main() {
 		},
 	}
 
+	// CLI command: aguaxaca backfill
+	backfillFlagSet := flag.NewFlagSet("backfill", flag.ExitOnError)
+	since := backfillFlagSet.String("since", "", "oldest publication date to collect (YYYY-MM-DD)")
+	maxPages := backfillFlagSet.Int("max-pages", 100, "maximum number of timeline pages to visit")
+	backfillCmd := &ffcli.Command{
+		Name:       "backfill",
+		ShortUsage: "aguaxaca backfill --since YYYY-MM-DD [--max-pages N]",
+		ShortHelp:  "Fetch older water schedules",
+		FlagSet:    backfillFlagSet,
+		Exec: func(context.Context, []string) error {
+			sinceDate, err := time.Parse(time.DateOnly, *since)
+			if err != nil {
+				return fmt.Errorf("invalid --since date '%s': %w", *since, err)
+			}
+
+			if err := app.DefaultCollector().Backfill(sinceDate, *maxPages); err != nil {
+				fmt.Printf("Error collecting schedules: %v\n", err)
+				os.Exit(2)
+			}
+
+			return nil
+		},
+	}
+
+	// CLI command: aguaxaca import
+	importFlagSet := flag.NewFlagSet("import", flag.ExitOnError)
+	postedAt := importFlagSet.String("posted-at", "", "publication date of the notices (YYYY-MM-DD)")
+	source := importFlagSet.String("source", collector.ManualSource, "where the notices come from")
+	postURL := importFlagSet.String("url", "", "link to the original notice")
+	analyzeNow := importFlagSet.Bool("analyze", false, "analyze the new imports right away")
+	importCmd := &ffcli.Command{
+		Name:       "import",
+		ShortUsage: "aguaxaca import [--posted-at YYYY-MM-DD] [--source NAME] [--url URL] [--analyze] PATH ...",
+		ShortHelp:  "Import image files, or directories of images",
+		FlagSet:    importFlagSet,
+		Exec: func(_ context.Context, args []string) error {
+			if len(args) == 0 {
+				return fmt.Errorf("no file to import")
+			}
+
+			files := app.NewFilesCollector(args)
+			files.Source = *source
+			files.URL = *postURL
+			if *postedAt != "" {
+				date, err := time.Parse(time.DateOnly, *postedAt)
+				if err != nil {
+					return fmt.Errorf("invalid --posted-at date '%s': %w", *postedAt, err)
+				}
+				files.PostedAt = date
+			}
+
+			imports, err := app.NewCollector(files, "files").CollectImports()
+			if err != nil {
+				fmt.Printf("Error importing files: %v\n", err)
+				os.Exit(2)
+			}
+			fmt.Printf("Import complete (%d).\n", len(imports))
+
+			if *analyzeNow && len(imports) > 0 {
+				count, err := app.NewAnalyzer(app.DefaultParser()).ProcessImports(imports)
+				if err != nil {
+					fmt.Printf("Error analyzing images: %v\n", err)
+				}
+				fmt.Printf("Image analysis complete (%d).\n", count)
+			}
+			return nil
+		},
+	}
+
 	// CLI command: aguaxaca analyze
 	analyzeCmd := &ffcli.Command{
 		Name:      "analyze",
 		ShortHelp: "Analyze and extract data from collected images",
 		Exec: func(context.Context, []string) error {
-			analyzer := app.NewAnalyzer()
+			analyzer := app.NewAnalyzer(app.DefaultParser())
 			count, err := analyzer.ProcessPendingImports()
 			if err != nil {
-				fmt.Printf("Error analyzing images: %v", err)
+				fmt.Printf("Error analyzing images: %v\n", err)
 			}
 			fmt.Printf("Image analysis complete (%d).\n", count)
 			return nil
 		},
 	}
 
+	// CLI command: aguaxaca reanalyze
+	reanalyzeFlagSet := flag.NewFlagSet("reanalyze", flag.ExitOnError)
+	importID := reanalyzeFlagSet.Int64("import", 0, "ID of the import to analyze again")
+	reanalyzeSince := reanalyzeFlagSet.String("since", "", "analyze again imports published since (YYYY-MM-DD)")
+	failed := reanalyzeFlagSet.Bool("failed", false, "analyze again failed imports")
+	cached := reanalyzeFlagSet.Bool("cached", false, "import stored responses again, without calling the parser")
+	reanalyzeCmd := &ffcli.Command{
+		Name:       "reanalyze",
+		ShortUsage: "aguaxaca reanalyze [--cached] (--import ID | --since YYYY-MM-DD | --failed)",
+		ShortHelp:  "Extract data again from selected images",
+		FlagSet:    reanalyzeFlagSet,
+		Exec: func(context.Context, []string) error {
+			filter := appPkg.ImportFilter{ID: *importID, Failed: *failed}
+			if *reanalyzeSince != "" {
+				date, err := time.Parse(time.DateOnly, *reanalyzeSince)
+				if err != nil {
+					return fmt.Errorf("invalid --since date '%s': %w", *reanalyzeSince, err)
+				}
+				filter.Since = date
+			}
+			selectors := 0
+			for _, set := range []bool{filter.ID != 0, !filter.Since.IsZero(), filter.Failed} {
+				if set {
+					selectors++
+				}
+			}
+			if selectors != 1 {
+				return fmt.Errorf("use one of --import, --since, or --failed")
+			}
+
+			analyzer := app.NewAnalyzer(app.DefaultParser())
+			imports, err := analyzer.FindImports(filter)
+			if err != nil {
+				return err
+			}
+
+			count, err := analyzer.Reanalyze(imports, *cached)
+			if err != nil {
+				fmt.Printf("Error analyzing images: %v\n", err)
+			}
+			fmt.Printf("Image analysis complete (%d/%d).\n", count, len(imports))
+			return nil
+		},
+	}
+
+	// CLI command: aguaxaca locations
+	locationsListCmd := &ffcli.Command{
+		Name:      "list",
+		ShortHelp: "List canonical locations",
+		Exec: func(context.Context, []string) error {
+			locations, err := app.NewLocations().List()
+			if err != nil {
+				return err
+			}
+			for _, location := range locations {
+				fmt.Printf("%s\t%s\t%s\n", location.Slug, location.LocationType, location.Name)
+			}
+			return nil
+		},
+	}
+	locationsReviewCmd := &ffcli.Command{
+		Name:      "review",
+		ShortHelp: "List location names that matched no location",
+		Exec: func(context.Context, []string) error {
+			reviews, err := app.NewLocations().Reviews()
+			if err != nil {
+				return err
+			}
+			for _, review := range reviews {
+				fmt.Printf("%d\t%s\t%s\n", review.Occurrences, review.LocationType, review.Name)
+			}
+			return nil
+		},
+	}
+	locationsAddFlagSet := flag.NewFlagSet("add", flag.ExitOnError)
+	locationType := locationsAddFlagSet.String("type", "colonia", "location type")
+	locationsAddCmd := &ffcli.Command{
+		Name:       "add",
+		ShortUsage: "aguaxaca locations add [--type TYPE] NAME",
+		ShortHelp:  "Add a canonical location",
+		FlagSet:    locationsAddFlagSet,
+		Exec: func(_ context.Context, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("expected one location name")
+			}
+			location, err := app.NewLocations().Add(args[0], *locationType)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Location added: %s\n", location.Slug)
+			return nil
+		},
+	}
+	locationsAliasCmd := &ffcli.Command{
+		Name:       "alias",
+		ShortUsage: "aguaxaca locations alias SLUG NAME",
+		ShortHelp:  "Add another name to a location",
+		Exec: func(_ context.Context, args []string) error {
+			if len(args) != 2 {
+				return fmt.Errorf("expected a location slug, and a name")
+			}
+			return app.NewLocations().AddAlias(args[0], args[1])
+		},
+	}
+	locationsRelinkCmd := &ffcli.Command{
+		Name:      "relink",
+		ShortHelp: "Match deliveries without a location again",
+		Exec: func(context.Context, []string) error {
+			count, err := app.NewLocations().Relink()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Linked deliveries: %d.\n", count)
+			return nil
+		},
+	}
+	locationsNormalizeCmd := &ffcli.Command{
+		Name:      "normalize",
+		ShortHelp: "Split location names of past deliveries again",
+		Exec: func(context.Context, []string) error {
+			count, err := app.NewLocations().Normalize()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Normalized deliveries: %d.\n", count)
+			return nil
+		},
+	}
+	locationsCmd := &ffcli.Command{
+		Name:       "locations",
+		ShortUsage: "aguaxaca locations SUBCOMMAND ...",
+		ShortHelp:  "Manage the gazetteer of canonical locations",
+		Subcommands: []*ffcli.Command{
+			locationsListCmd, locationsReviewCmd, locationsAddCmd, locationsAliasCmd, locationsRelinkCmd,
+			locationsNormalizeCmd,
+		},
+		Exec: func(context.Context, []string) error {
+			return flag.ErrHelp
+		},
+	}
+
+	// CLI command: aguaxaca quarantine
+	quarantineListCmd := &ffcli.Command{
+		Name:      "list",
+		ShortHelp: "List quarantined deliveries",
+		Exec: func(context.Context, []string) error {
+			rows, err := app.NewQuarantine().List()
+			if err != nil {
+				return err
+			}
+			for _, row := range rows {
+				fmt.Printf("%d\t#%d\t%s\t%s\t%s\t%s\t%s\n", row.ID, row.ImportID, row.Date,
+					row.Schedule, row.LocationType, row.LocationName, row.Reason)
+			}
+			return nil
+		},
+	}
+	quarantineApproveFlagSet := flag.NewFlagSet("approve", flag.ExitOnError)
+	fixDate := quarantineApproveFlagSet.String("date", "", "replace the date (YYYY-MM-DD)")
+	fixSchedule := quarantineApproveFlagSet.String("schedule", "", "replace the schedule")
+	fixType := quarantineApproveFlagSet.String("location-type", "", "replace the location type")
+	fixName := quarantineApproveFlagSet.String("location-name", "", "replace the location name")
+	fixNotes := quarantineApproveFlagSet.String("notes", "", "replace the notes")
+	quarantineApproveCmd := &ffcli.Command{
+		Name:       "approve",
+		ShortUsage: "aguaxaca quarantine approve [--date D] [--schedule S] [--location-type T] [--location-name N] [--notes N] ID",
+		ShortHelp:  "Store a quarantined delivery, fixed or not",
+		FlagSet:    quarantineApproveFlagSet,
+		Exec: func(_ context.Context, args []string) error {
+			id, err := parseID(args)
+			if err != nil {
+				return err
+			}
+			delivery, err := app.NewQuarantine().Approve(cliActor(), id, parser.Delivery{
+				Date:         *fixDate,
+				Schedule:     *fixSchedule,
+				LocationType: *fixType,
+				LocationName: *fixName,
+				Notes:        *fixNotes,
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Delivery added: #%d.\n", delivery.ID)
+			return nil
+		},
+	}
+	quarantineDeleteCmd := &ffcli.Command{
+		Name:       "delete",
+		ShortUsage: "aguaxaca quarantine delete ID",
+		ShortHelp:  "Drop a quarantined delivery",
+		Exec: func(_ context.Context, args []string) error {
+			id, err := parseID(args)
+			if err != nil {
+				return err
+			}
+			return app.NewQuarantine().Delete(cliActor(), id)
+		},
+	}
+	quarantineCmd := &ffcli.Command{
+		Name:        "quarantine",
+		ShortUsage:  "aguaxaca quarantine SUBCOMMAND ...",
+		ShortHelp:   "Review deliveries that failed validation",
+		Subcommands: []*ffcli.Command{quarantineListCmd, quarantineApproveCmd, quarantineDeleteCmd},
+		Exec: func(context.Context, []string) error {
+			return flag.ErrHelp
+		},
+	}
+
+	// CLI command: aguaxaca imports
+	importsDeadCmd := &ffcli.Command{
+		Name:      "dead",
+		ShortHelp: "List imports that failed too many times",
+		Exec: func(context.Context, []string) error {
+			imports, err := app.NewReview().DeadImports()
+			if err != nil {
+				return err
+			}
+			for _, im := range imports {
+				fmt.Printf("%d\t%s\t%d runs\t%s\t%s\n", im.ID, im.DeadAt.Time.Format(time.DateTime),
+					im.Runs.Int64, im.FilePath, im.LastError)
+			}
+			return nil
+		},
+	}
+	importsReviveCmd := &ffcli.Command{
+		Name:       "revive",
+		ShortUsage: "aguaxaca imports revive ID",
+		ShortHelp:  "Queue a dead import for analysis again",
+		Exec: func(_ context.Context, args []string) error {
+			id, err := parseID(args)
+			if err != nil {
+				return err
+			}
+			if _, err := app.NewReview().ReviveImport(cliActor(), id); err != nil {
+				return err
+			}
+			fmt.Printf("Import revived: #%d.\n", id)
+			return nil
+		},
+	}
+	importsCmd := &ffcli.Command{
+		Name:        "imports",
+		ShortUsage:  "aguaxaca imports SUBCOMMAND ...",
+		ShortHelp:   "Manage imports that failed analysis",
+		Subcommands: []*ffcli.Command{importsDeadCmd, importsReviveCmd},
+		Exec: func(context.Context, []string) error {
+			return flag.ErrHelp
+		},
+	}
+
+	// CLI command: aguaxaca migrate
+	migrateStatusCmd := &ffcli.Command{
+		Name:      "status",
+		ShortHelp: "List migrations, applied or pending",
+		Exec: func(context.Context, []string) error {
+			migrations, err := app.NewMigrator().Status()
+			if err != nil {
+				return err
+			}
+			for _, m := range migrations {
+				status := "pending"
+				if m.AppliedAt != nil {
+					status = "applied " + m.AppliedAt.Time.Format(time.DateTime)
+				}
+				fmt.Printf("%04d\t%s\t%s\n", m.Version, m.Name, status)
+			}
+			return nil
+		},
+	}
+	migrateUpCmd := &ffcli.Command{
+		Name:      "up",
+		ShortHelp: "Apply pending migrations",
+		Exec: func(context.Context, []string) error {
+			count, err := app.NewMigrator().Up()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Applied migrations: %d.\n", count)
+			return nil
+		},
+	}
+	migrateDownCmd := &ffcli.Command{
+		Name:      "down",
+		ShortHelp: "Revert the latest migration",
+		Exec: func(context.Context, []string) error {
+			m, err := app.NewMigrator().Down()
+			if err != nil {
+				return err
+			}
+			if m == nil {
+				fmt.Println("No migration to revert.")
+				return nil
+			}
+			fmt.Printf("Reverted migration: %04d_%s.\n", m.Version, m.Name)
+			return nil
+		},
+	}
+	migrateCmd := &ffcli.Command{
+		Name:        "migrate",
+		ShortUsage:  "aguaxaca migrate [status|up|down]",
+		ShortHelp:   "Manage DB schema migrations",
+		Subcommands: []*ffcli.Command{migrateStatusCmd, migrateUpCmd, migrateDownCmd},
+		Exec:        migrateStatusCmd.Exec,
+	}
+
 	// CLI command: aguaxaca server
 	serverCmd := &ffcli.Command{
 		Name:      "server",
@@ -74,26 +462,33 @@ func main() {
 
 	// root command
 	rootFlagSet := flag.NewFlagSet("aguaxaca", flag.ExitOnError)
-	debug := rootFlagSet.Bool("debug", false, "log debug information")
-	listenAddr := rootFlagSet.String("listen", "localhost:8080", "listen address")
+	rootFlagSet.String("config", "", "config file (optional)")
+	app.Config.RegisterFlags(rootFlagSet)
 	root := &ffcli.Command{
 		Name:        "aguaxaca",
 		ShortUsage:  "aguaxaca [OPTIONS] SUBCOMMAND ...",
 		FlagSet:     rootFlagSet,
-		Subcommands: []*ffcli.Command{collectCmd, analyzeCmd, serverCmd},
+		Options:     appPkg.FlagOptions(),
+		Subcommands: []*ffcli.Command{collectCmd, backfillCmd, importCmd, analyzeCmd, reanalyzeCmd, importsCmd, locationsCmd, quarantineCmd, migrateCmd, serverCmd},
 		Exec: func(context.Context, []string) error {
 			// The root command by itself has no use. Show usage help.
 			return flag.ErrHelp
 		},
 	}
 
+	if err := appPkg.LoadLegacyEnv(rootFlagSet, app.Logger); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
 	if err := root.Parse(os.Args[1:]); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Configure App after flags parsing.
-	if err := app.Init(*debug, *listenAddr); err != nil {
+	// Configure App after flags parsing. The migrate command manages the
+	// schema by itself.
+	app.AutoMigrate = rootFlagSet.Arg(0) != migrateCmd.Name
+	if err := app.Init(); err != nil {
 		fmt.Fprintf(os.Stderr, "App init error: %v\n", err)
 		os.Exit(2)
 	}
@@ -103,3 +498,23 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// parseID reads the single ID argument of a command.
+func parseID(args []string) (int64, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("expected one ID")
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ID '%s'", args[0])
+	}
+	return id, nil
+}
+
+// cliActor names who runs a command, in the audit log.
+func cliActor() string {
+	if user := os.Getenv("USER"); user != "" {
+		return "cli:" + user
+	}
+	return "cli"
+}
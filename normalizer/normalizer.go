@@ -0,0 +1,232 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package normalizer splits location names, as written in public
+// notices, into a base name and its qualifiers: "Guadalupe Victoria
+// (sector 1, 2ª sección Oeste)" is sector 1 of the 2nd western section
+// of Guadalupe Victoria.
+package normalizer
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"git.cypr.io/oz/aguaxaca/gazetteer"
+)
+
+// Name is a location name split in parts. Qualifiers that are not
+// understood stay in Base.
+type Name struct {
+	Base        string
+	Sectors     []string // sorted, numbers first
+	Section     string   // a number: "2" for "2ª sección"
+	Orientation string   // see Orientations
+}
+
+// Orientations maps the cardinal points found in notices to their
+// canonical form.
+var Orientations = map[string]string{
+	"norte":    "norte",
+	"sur":      "sur",
+	"oriente":  "oriente",
+	"este":     "oriente",
+	"poniente": "poniente",
+	"oeste":    "poniente",
+	"centro":   "centro",
+}
+
+// Ordinals of sections, written in full.
+var ordinals = map[string]string{
+	"primera": "1", "segunda": "2", "tercera": "3", "cuarta": "4", "quinta": "5",
+	"sexta": "6", "septima": "7", "octava": "8", "novena": "9", "decima": "10",
+}
+
+// Roman numerals of sections.
+var romans = map[string]string{
+	"i": "1", "ii": "2", "iii": "3", "iv": "4", "v": "5",
+	"vi": "6", "vii": "7", "viii": "8", "ix": "9", "x": "10",
+}
+
+const (
+	sectorPattern  = `sector(?:es)?\s+(\S+(?:(?:\s*,\s*|\s+y\s+)\S+)*)`
+	ordinalPattern = `(\d+)\s*(?:era|ra|da|er|ta|va|na|ª|º|°|a|o)?\.?|primera|segunda|tercera|cuarta|quinta|sexta|s[eé]ptima|octava|novena|d[eé]cima`
+	sectionPattern = `(?:(?:` + ordinalPattern + `)\s+secc(?:i[oó]n|\.)|secc(?:i[oó]n|\.)\s*(\d+|[ivx]+\b))`
+	orientPattern  = `(?:\s+(norte|sur|oriente|este|poniente|oeste|centro))?`
+)
+
+var (
+	// Qualifiers between parentheses.
+	parenRe = regexp.MustCompile(`\(([^()]*)\)`)
+
+	// Qualifiers, alone or at the end of a name.
+	sectorRe      = regexp.MustCompile(`(?i)^(.*?)\s*\b` + sectorPattern + `$`)
+	sectionRe     = regexp.MustCompile(`(?i)^(.*?)\s*(?:^|\s)` + sectionPattern + orientPattern + `$`)
+	orientationRe = regexp.MustCompile(`(?i)^(norte|sur|oriente|este|poniente|oeste|centro)$`)
+
+	// Sectors at the end of a name, outside parentheses, must be numbers
+	// or letters: "Ampliación Sector Reforma" is a name.
+	shortSectorsRe = regexp.MustCompile(`(?i)^(?:\d+|[a-z]{1,4})(?:(?:\s*,\s*|\s+y\s+)(?:\d+|[a-z]{1,4}))*$`)
+
+	// More sectors, after a list of sectors: "2 y 3" in "sectores 1, 2 y 3".
+	sectorListRe = regexp.MustCompile(`(?i)^\S+(?:\s+y\s+\S+)*$`)
+
+	listSepRe = regexp.MustCompile(`(?i)\s*,\s*|\s+y\s+`)
+)
+
+// Parse splits a location name in parts.
+func Parse(name string) Name {
+	n := Name{}
+	base := parenRe.ReplaceAllStringFunc(name, func(paren string) string {
+		if n.parseQualifiers(strings.Trim(paren, "()")) {
+			return " "
+		}
+		return paren
+	})
+
+	// Qualifiers at the end of the name.
+	base = n.parseSection(base)
+	if rest, sectors, ok := matchSectors(base); ok && rest != "" && shortSectorsRe.MatchString(sectors) {
+		base = rest
+		n.addSectors(sectors)
+		base = n.parseSection(base)
+	}
+
+	n.Base = strings.Trim(strings.Join(strings.Fields(base), " "), " ,;-")
+	slices.SortStableFunc(n.Sectors, compareSectors)
+	n.Sectors = slices.Compact(n.Sectors)
+	return n
+}
+
+// SectorList returns the sectors, separated by commas.
+func (n Name) SectorList() string {
+	return strings.Join(n.Sectors, ",")
+}
+
+// parseQualifiers reads a list of qualifiers, found between parentheses,
+// and returns false if any of them is not understood.
+func (n *Name) parseQualifiers(qualifiers string) bool {
+	parsed := Name{}
+	inSectors := false
+	for part := range strings.FieldsFuncSeq(qualifiers, func(r rune) bool { return r == ',' || r == ';' }) {
+		part = strings.TrimSpace(part)
+		switch {
+		case part == "":
+			continue
+		case inSectors && sectorListRe.MatchString(part) && !orientationRe.MatchString(part):
+			parsed.addSectors(part)
+			continue
+		}
+		inSectors = false
+
+		if rest, sectors, ok := matchSectors(part); ok && rest == "" {
+			parsed.addSectors(sectors)
+			inSectors = true
+		} else if m := orientationRe.FindStringSubmatch(part); m != nil && parsed.Orientation == "" {
+			parsed.Orientation = Orientations[strings.ToLower(m[1])]
+		} else if rest := parsed.parseSection(part); rest != part && strings.TrimSpace(rest) == "" {
+			continue
+		} else {
+			return false
+		}
+	}
+
+	n.Sectors = append(n.Sectors, parsed.Sectors...)
+	if parsed.Section != "" {
+		n.Section = parsed.Section
+	}
+	if parsed.Orientation != "" {
+		n.Orientation = parsed.Orientation
+	}
+	return true
+}
+
+// parseSection reads a section (and its orientation) at the end of s,
+// and returns the rest of s.
+func (n *Name) parseSection(s string) string {
+	m := sectionRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return s
+	}
+
+	section := ""
+	switch {
+	case m[2] != "":
+		section = m[2]
+	case m[3] != "":
+		section = romans[strings.ToLower(m[3])]
+		if section == "" {
+			section = m[3]
+		}
+	default:
+		// An ordinal in full: the first word of the match.
+		word := strings.Fields(strings.TrimSpace(strings.TrimPrefix(m[0], m[1])))[0]
+		section = ordinals[gazetteer.Fold(word)]
+	}
+	if section == "" {
+		return s
+	}
+	if n, err := strconv.Atoi(section); err == nil {
+		section = strconv.Itoa(n) // "02" is "2"
+	}
+
+	n.Section = section
+	if m[4] != "" {
+		n.Orientation = Orientations[strings.ToLower(m[4])]
+	}
+	return m[1]
+}
+
+func (n *Name) addSectors(list string) {
+	for _, sector := range listSepRe.Split(strings.TrimSpace(list), -1) {
+		sector = strings.Trim(sector, " .")
+		if sector == "" {
+			continue
+		}
+		if num, err := strconv.Atoi(sector); err == nil {
+			sector = strconv.Itoa(num)
+		} else if len([]rune(sector)) <= 4 {
+			sector = strings.ToUpper(sector) // letters, and roman numerals
+		}
+		n.Sectors = append(n.Sectors, sector)
+	}
+}
+
+// matchSectors finds a list of sectors at the end of s, and returns
+// what precedes it.
+func matchSectors(s string) (rest string, sectors string, ok bool) {
+	m := sectorRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return s, "", false
+	}
+	return m[1], m[2], true
+}
+
+// compareSectors sorts numbers first, in numeric order, then names.
+func compareSectors(a, b string) int {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return na - nb
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,98 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package normalizer
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		want Name
+	}{
+		// Names without qualifiers.
+		{"Libertad", Name{Base: "Libertad"}},
+		{"  Jardines de Las Lomas ", Name{Base: "Jardines de Las Lomas"}},
+		{"20 de Noviembre", Name{Base: "20 de Noviembre"}},
+		{"5 Señores", Name{Base: "5 Señores"}},
+		{"Ampliación Sector Reforma", Name{Base: "Ampliación Sector Reforma"}},
+
+		// Sectors.
+		{"Jardín (sector Bugambilias)", Name{Base: "Jardín", Sectors: []string{"Bugambilias"}}},
+		{"Guadalupe Victoria (sectores 1, 2 y 3)", Name{Base: "Guadalupe Victoria", Sectors: []string{"1", "2", "3"}}},
+		{"Colonia del Maestro (sector 10, 2)", Name{Base: "Colonia del Maestro", Sectors: []string{"2", "10"}}},
+		{"Infonavit Primero de Mayo (sector A y B)", Name{Base: "Infonavit Primero de Mayo", Sectors: []string{"A", "B"}}},
+		{"5 Señores (Sector II)", Name{Base: "5 Señores", Sectors: []string{"II"}}},
+		{"Ampliación Dolores (sector Norte)", Name{Base: "Ampliación Dolores", Sectors: []string{"Norte"}}},
+		{"Santa Rosa Panzacola sector 3", Name{Base: "Santa Rosa Panzacola", Sectors: []string{"3"}}},
+		{"Niños Héroes (sector 1; sector 2)", Name{Base: "Niños Héroes", Sectors: []string{"1", "2"}}},
+
+		// Sections, and orientations.
+		{"San Martín Mexicapam 2ª Sección", Name{Base: "San Martín Mexicapam", Section: "2"}},
+		{"Reforma Agraria (Primera Sección)", Name{Base: "Reforma Agraria", Section: "1"}},
+		{"Guadalupe Victoria 1ra. sección", Name{Base: "Guadalupe Victoria", Section: "1"}},
+		{"Lomas del Crestón (sección 2 sur)", Name{Base: "Lomas del Crestón", Section: "2", Orientation: "sur"}},
+		{"Lomas de San Jacinto Sección III", Name{Base: "Lomas de San Jacinto", Section: "3"}},
+		{"Volcanes (Norte)", Name{Base: "Volcanes", Orientation: "norte"}},
+		{"Jalatlaco (Oriente)", Name{Base: "Jalatlaco", Orientation: "oriente"}},
+
+		// All of it.
+		{
+			"Guadalupe Victoria (sector 1, 2ª sección Oeste)",
+			Name{Base: "Guadalupe Victoria", Sectors: []string{"1"}, Section: "2", Orientation: "poniente"},
+		},
+		{
+			"Guadalupe Victoria 2a sección (sectores 3 y 1)",
+			Name{Base: "Guadalupe Victoria", Sectors: []string{"1", "3"}, Section: "2"},
+		},
+
+		// Unknown qualifiers stay in the name.
+		{"Ex-Hacienda Candiani (parte alta)", Name{Base: "Ex-Hacienda Candiani (parte alta)"}},
+		{"Miguel Alemán (sector 1, parte baja)", Name{Base: "Miguel Alemán (sector 1, parte baja)"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.name)
+			if got.Base != tt.want.Base ||
+				!slices.Equal(got.Sectors, tt.want.Sectors) ||
+				got.Section != tt.want.Section ||
+				got.Orientation != tt.want.Orientation {
+				t.Errorf("Parse(%q) = %#v, want %#v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSectorList(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Libertad", ""},
+		{"Guadalupe Victoria (sector 2)", "2"},
+		{"Guadalupe Victoria (sectores 2, 1 y 2)", "1,2"},
+	}
+
+	for _, tt := range tests {
+		if got := Parse(tt.name).SectorList(); got != tt.want {
+			t.Errorf("Parse(%q).SectorList() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// DefaultAnthropicModel is the model used to read images.
+const DefaultAnthropicModel = anthropic.ModelClaude4Sonnet20250514
+
+// DeliveriesTool is the name of the tool Anthropic's models must use to
+// report deliveries, with DeliveriesSchema as its input schema.
+const DeliveriesTool = "report_deliveries"
+
+// AnthropicParser reads images with Anthropic's Messages API.
+type AnthropicParser struct {
+	Model     anthropic.Model
+	MaxTokens int64
+	client    anthropic.Client
+}
+
+// NewAnthropicParser builds a parser using the default model.
+// The API key is set with: os.LookupEnv("ANTHROPIC_API_KEY")
+func NewAnthropicParser() *AnthropicParser {
+	return &AnthropicParser{
+		Model:     DefaultAnthropicModel,
+		MaxTokens: 20000,
+		client:    anthropic.NewClient(),
+	}
+}
+
+// ParseFile queries Anthropic with a file attachment, prompting as indicated, and returns the resulting text.
+func (p *AnthropicParser) ParseFile(ctx context.Context, filePath string, prompt string) (*Response, error) {
+	file, mediaType, err := readImage(filePath)
+	if err != nil {
+		return nil, err
+	}
+	encodedData := base64.StdEncoding.EncodeToString(file)
+
+	res, err := p.client.Messages.New(ctx, anthropic.MessageNewParams{
+		MaxTokens: p.MaxTokens,
+		Model:     p.Model,
+		Messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(
+				anthropic.NewTextBlock(prompt),
+				anthropic.NewImageBlockBase64(mediaType, encodedData),
+			),
+		},
+		Tools:      []anthropic.ToolUnionParam{deliveriesTool()},
+		ToolChoice: anthropic.ToolChoiceParamOfTool(DeliveriesTool),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Anthropic error for %s: %w", filePath, err)
+	}
+
+	response := &Response{
+		Model:        string(res.Model),
+		InputTokens:  res.Usage.InputTokens,
+		OutputTokens: res.Usage.OutputTokens,
+	}
+
+	// The tool's input is the JSON document we're after.
+	for _, block := range res.Content {
+		if block.Type == "tool_use" && block.Name == DeliveriesTool {
+			response.Text = string(block.Input)
+			return response, nil
+		}
+		response.Text += block.Text
+	}
+	return response, fmt.Errorf("Anthropic did not use the %s tool for %s", DeliveriesTool, filePath)
+}
+
+// deliveriesTool describes the tool used for structured output.
+func deliveriesTool() anthropic.ToolUnionParam {
+	tool := anthropic.ToolUnionParamOfTool(anthropic.ToolInputSchemaParam{
+		Properties: DeliveriesSchema["properties"],
+		Required:   []string{"deliveries"},
+	}, DeliveriesTool)
+	tool.OfTool.Description = anthropic.String("Report the water deliveries announced in the image.")
+	return tool
+}
@@ -0,0 +1,203 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// DateFormat is the format of date fields in the parsers' output.
+const DateFormat = "2006-01-02"
+
+// Delivery is a water delivery, as extracted from a notice.
+type Delivery struct {
+	Date         string `json:"date"`
+	Schedule     string `json:"schedule"`
+	LocationType string `json:"location_type"`
+	LocationName string `json:"location_name"`
+	Notes        string `json:"notes,omitempty"`
+}
+
+// deliveries is the structured output of parsers.
+type deliveries struct {
+	Deliveries []Delivery `json:"deliveries"`
+}
+
+// deliveryProperties is the JSON schema of a Delivery.
+var deliveryProperties = map[string]any{
+	"date": map[string]any{
+		"type":        "string",
+		"description": `Delivery date, formatted as "YYYY-MM-DD".`,
+		"pattern":     `^[0-9]{4}-[0-9]{2}-[0-9]{2}$`,
+	},
+	"schedule": map[string]any{
+		"type":        "string",
+		"description": `Schedule, like "matutino", "vespertino", or "nocturno", downcased.`,
+	},
+	"location_type": map[string]any{
+		"type":        "string",
+		"description": `Location type, like "colonia", or "fraccionamiento", in singular form and downcased.`,
+	},
+	"location_name": map[string]any{
+		"type":        "string",
+		"description": "Location name, with its qualifiers (sector, sección, ...).",
+	},
+	"notes": map[string]any{
+		"type":        "string",
+		"description": "Optional remarks about this delivery.",
+	},
+}
+
+// DeliveriesSchema is the JSON schema of the parsers' structured output.
+var DeliveriesSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"deliveries": map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type":                 "object",
+				"properties":           deliveryProperties,
+				"required":             []string{"date", "schedule", "location_type", "location_name"},
+				"additionalProperties": false,
+			},
+		},
+	},
+	"required":             []string{"deliveries"},
+	"additionalProperties": false,
+}
+
+// csvHeader lists the columns of the legacy CSV output.
+var csvHeader = []string{"date", "schedule", "location_type", "location_name"}
+
+// DecodeDeliveries decodes, and validates, a parser's response: a JSON
+// document matching DeliveriesSchema, or legacy CSV with a header row.
+// Markdown code fences around the document are ignored, but any other
+// text is an error.
+func DecodeDeliveries(response string) ([]Delivery, error) {
+	doc := trimCodeFence(strings.TrimSpace(response))
+
+	var list []Delivery
+	var err error
+	if strings.HasPrefix(doc, "{") {
+		list, err = decodeJSON(doc)
+	} else {
+		list, err = decodeCSV(doc)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range list {
+		if err := list[i].validate(); err != nil {
+			return nil, fmt.Errorf("invalid delivery #%d: %w", i+1, err)
+		}
+	}
+	return list, nil
+}
+
+func decodeJSON(doc string) ([]Delivery, error) {
+	dec := json.NewDecoder(strings.NewReader(doc))
+	dec.DisallowUnknownFields()
+
+	var data deliveries
+	if err := dec.Decode(&data); err != nil {
+		return nil, fmt.Errorf("error decoding JSON: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after JSON document")
+	}
+	if data.Deliveries == nil {
+		return nil, fmt.Errorf("missing deliveries in JSON document")
+	}
+	return data.Deliveries, nil
+}
+
+func decodeCSV(doc string) ([]Delivery, error) {
+	reader := csv.NewReader(strings.NewReader(doc))
+	reader.FieldsPerRecord = len(csvHeader)
+
+	// Read header row
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading CSV header: %w", err)
+	}
+	for i, column := range header {
+		if strings.ToLower(strings.TrimSpace(column)) != csvHeader[i] {
+			return nil, fmt.Errorf("unexpected CSV column '%s', expected '%s'", column, csvHeader[i])
+		}
+	}
+
+	list := []Delivery{}
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("error reading CSV record: %w", err)
+		}
+
+		list = append(list, Delivery{
+			Date:         record[0],
+			Schedule:     record[1],
+			LocationType: record[2],
+			LocationName: record[3],
+		})
+	}
+	return list, nil
+}
+
+// validate trims fields, and checks that required ones are set.
+func (d *Delivery) validate() error {
+	d.Date = strings.TrimSpace(d.Date)
+	d.Schedule = strings.TrimSpace(d.Schedule)
+	d.LocationType = strings.TrimSpace(d.LocationType)
+	d.LocationName = strings.TrimSpace(d.LocationName)
+	d.Notes = strings.TrimSpace(d.Notes)
+
+	if _, err := time.Parse(DateFormat, d.Date); err != nil {
+		return fmt.Errorf("invalid date format '%s'", d.Date)
+	}
+	if d.Schedule == "" {
+		return fmt.Errorf("missing schedule")
+	}
+	if d.LocationType == "" {
+		return fmt.Errorf("missing location type")
+	}
+	if d.LocationName == "" {
+		return fmt.Errorf("missing location name")
+	}
+	return nil
+}
+
+// trimCodeFence removes a markdown code fence (```json ... ```) around
+// doc, if there's one.
+func trimCodeFence(doc string) string {
+	if !strings.HasPrefix(doc, "```") || !strings.HasSuffix(doc, "```") {
+		return doc
+	}
+	lines := strings.Split(doc, "\n")
+	if len(lines) < 2 {
+		return doc
+	}
+	return strings.TrimSpace(strings.Join(lines[1:len(lines)-1], "\n"))
+}
@@ -0,0 +1,125 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDecodeDeliveries(t *testing.T) {
+	libertad := Delivery{Date: "2025-07-21", Schedule: "matutino", LocationType: "colonia", LocationName: "Libertad"}
+	centro := Delivery{Date: "2025-07-22", Schedule: "nocturno", LocationType: "barrio", LocationName: "Centro", Notes: "pipa"}
+
+	tests := []struct {
+		name     string
+		response string
+		want     []Delivery
+		wantErr  bool
+	}{
+		// JSON.
+		{
+			"json",
+			`{"deliveries": [{"date": "2025-07-21", "schedule": "matutino", "location_type": "colonia", "location_name": "Libertad"}]}`,
+			[]Delivery{libertad}, false,
+		},
+		{
+			"json with notes, and spaces to trim",
+			`{"deliveries": [{"date": " 2025-07-22", "schedule": "nocturno ", "location_type": "barrio", "location_name": " Centro ", "notes": " pipa"}]}`,
+			[]Delivery{centro}, false,
+		},
+		{"json without deliveries", `{"deliveries": []}`, []Delivery{}, false},
+		{
+			"fenced json",
+			"```json\n{\"deliveries\": [{\"date\": \"2025-07-21\", \"schedule\": \"matutino\", \"location_type\": \"colonia\", \"location_name\": \"Libertad\"}]}\n```",
+			[]Delivery{libertad}, false,
+		},
+		{
+			"fence without language",
+			"```\n{\"deliveries\": []}\n```",
+			[]Delivery{}, false,
+		},
+		{"prose before json", `Here are the deliveries: {"deliveries": []}`, nil, true},
+		{"prose after json", `{"deliveries": []} Hope this helps!`, nil, true},
+		{"prose around fence", "Sure!\n```json\n{\"deliveries\": []}\n```", nil, true},
+		{"missing deliveries", `{}`, nil, true},
+		{"unknown field", `{"deliveries": [], "date": "2025-07-21"}`, nil, true},
+		{
+			"unknown delivery field",
+			`{"deliveries": [{"date": "2025-07-21", "schedule": "matutino", "location_type": "colonia", "location_name": "Libertad", "sector": "1"}]}`,
+			nil, true,
+		},
+		{"truncated json", `{"deliveries": [{"date": "2025-07-21"`, nil, true},
+
+		// Legacy CSV.
+		{
+			"csv",
+			"date,schedule,location_type,location_name\n2025-07-21,matutino,colonia,Libertad\n",
+			[]Delivery{libertad}, false,
+		},
+		{
+			"csv with header in caps, and quotes",
+			"Date, Schedule, Location_Type, Location_Name\n2025-07-21,matutino,colonia,\"Libertad\"\n2025-07-22,nocturno,barrio,Centro",
+			[]Delivery{libertad, {Date: "2025-07-22", Schedule: "nocturno", LocationType: "barrio", LocationName: "Centro"}}, false,
+		},
+		{"csv header only", "date,schedule,location_type,location_name", []Delivery{}, false},
+		{"csv without header", "2025-07-21,matutino,colonia,Libertad\n", nil, true},
+		{"csv with unexpected header", "fecha,horario,tipo,nombre\n2025-07-21,matutino,colonia,Libertad\n", nil, true},
+		{"csv with extra column", "date,schedule,location_type,location_name\n2025-07-21,matutino,colonia,Libertad,pipa\n", nil, true},
+		{"csv with missing column", "date,schedule,location_type,location_name\n2025-07-21,matutino,Libertad\n", nil, true},
+		{"empty", "", nil, true},
+
+		// Rows rejected by validate.
+		{"bad date", "date,schedule,location_type,location_name\n21/07/2025,matutino,colonia,Libertad\n", nil, true},
+		{"impossible date", "date,schedule,location_type,location_name\n2025-02-30,matutino,colonia,Libertad\n", nil, true},
+		{
+			"bad json date",
+			`{"deliveries": [{"date": "2025-7-21", "schedule": "matutino", "location_type": "colonia", "location_name": "Libertad"}]}`,
+			nil, true,
+		},
+		{"missing schedule", "date,schedule,location_type,location_name\n2025-07-21, ,colonia,Libertad\n", nil, true},
+		{"missing location type", "date,schedule,location_type,location_name\n2025-07-21,matutino,,Libertad\n", nil, true},
+		{
+			"missing location name",
+			`{"deliveries": [{"date": "2025-07-21", "schedule": "matutino", "location_type": "colonia", "location_name": "  "}]}`,
+			nil, true,
+		},
+		{
+			"one invalid row among valid ones",
+			"date,schedule,location_type,location_name\n2025-07-21,matutino,colonia,Libertad\n2025-07-22,nocturno,,Centro\n",
+			nil, true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeDeliveries(tt.response)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("DecodeDeliveries(%q) = %#v, want an error", tt.response, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecodeDeliveries(%q): %v", tt.response, err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("DecodeDeliveries(%q) = %#v, want %#v", tt.response, got, tt.want)
+			}
+		})
+	}
+}
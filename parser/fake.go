@@ -0,0 +1,68 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FakeResponse is the default canned response of FakeParser.
+const FakeResponse = `{"deliveries": [
+  {"date": "2025-07-21", "schedule": "matutino-vespertino", "location_type": "colonia", "location_name": "Libertad"},
+  {"date": "2025-07-21", "schedule": "matutino-vespertino", "location_type": "colonia", "location_name": "Jardín (sector Bugambilias)"},
+  {"date": "2025-07-21", "schedule": "matutino-vespertino", "location_type": "fraccionamiento", "location_name": "Jardines de Las Lomas"},
+  {"date": "2025-07-21", "schedule": "matutino-vespertino", "location_type": "ejido", "location_name": "Guadalupe Victoria (sector 1, 2ª sección Oeste)"},
+  {"date": "2025-07-21", "schedule": "nocturno", "location_type": "unidad", "location_name": "Ferrocarrilera"}
+]}`
+
+// FakeModel is the model name reported by FakeParser.
+const FakeModel = "fake"
+
+// FakeParser serves canned responses, without reading images, to run
+// the pipeline offline. For an image "foo.jpg", it returns the content
+// of "foo.txt" in Dir, or of "default.txt", or FakeResponse.
+type FakeParser struct {
+	Dir string
+}
+
+// NewFakeParser builds a parser with canned responses in dir.
+func NewFakeParser(dir string) *FakeParser {
+	return &FakeParser{Dir: dir}
+}
+
+// ParseFile returns the canned response for filePath, ignoring the prompt.
+func (p *FakeParser) ParseFile(ctx context.Context, filePath string, prompt string) (*Response, error) {
+	if p.Dir == "" {
+		return &Response{Text: FakeResponse, Model: FakeModel}, nil
+	}
+
+	name := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	for _, candidate := range []string{name + ".txt", "default.txt"} {
+		data, err := os.ReadFile(filepath.Join(p.Dir, candidate))
+		if err == nil {
+			return &Response{Text: string(data), Model: FakeModel}, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("can't read canned response for %s: %w", filePath, err)
+		}
+	}
+	return &Response{Text: FakeResponse, Model: FakeModel}, nil
+}
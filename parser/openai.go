@@ -0,0 +1,176 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultOpenAIBaseURL is Ollama's OpenAI-compatible API.
+const DefaultOpenAIBaseURL = "http://localhost:11434/v1"
+
+// OpenAIParser reads images with any OpenAI-compatible chat completion
+// API, like a local llama.cpp, or Ollama server. The model must support
+// image inputs.
+type OpenAIParser struct {
+	BaseURL    string
+	APIKey     string // optional with local servers
+	Model      string
+	MaxTokens  int64
+	HTTPClient *http.Client
+}
+
+type openAIMessage struct {
+	Role    string          `json:"role"`
+	Content []openAIContent `json:"content"`
+}
+
+type openAIContent struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+type openAIResponseFormat struct {
+	Type       string           `json:"type"`
+	JSONSchema openAIJSONSchema `json:"json_schema"`
+}
+
+type openAIJSONSchema struct {
+	Name   string         `json:"name"`
+	Schema map[string]any `json:"schema"`
+}
+
+type openAIRequest struct {
+	Model          string               `json:"model"`
+	MaxTokens      int64                `json:"max_tokens,omitempty"`
+	Messages       []openAIMessage      `json:"messages"`
+	ResponseFormat openAIResponseFormat `json:"response_format"`
+}
+
+type openAIResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int64 `json:"prompt_tokens"`
+		CompletionTokens int64 `json:"completion_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// NewOpenAIParser builds a parser for model, served at baseURL.
+func NewOpenAIParser(baseURL string, model string) *OpenAIParser {
+	return &OpenAIParser{
+		BaseURL:    baseURL,
+		Model:      model,
+		MaxTokens:  20000,
+		HTTPClient: &http.Client{Timeout: 10 * time.Minute},
+	}
+}
+
+// ParseFile sends a file as a data URL, prompting as indicated, and returns the resulting text.
+func (p *OpenAIParser) ParseFile(ctx context.Context, filePath string, prompt string) (*Response, error) {
+	file, mediaType, err := readImage(filePath)
+	if err != nil {
+		return nil, err
+	}
+	dataURL := "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(file)
+
+	body, err := json.Marshal(openAIRequest{
+		Model:     p.Model,
+		MaxTokens: p.MaxTokens,
+		Messages: []openAIMessage{{
+			Role: "user",
+			Content: []openAIContent{
+				{Type: "text", Text: prompt},
+				{Type: "image_url", ImageURL: &openAIImageURL{URL: dataURL}},
+			},
+		}},
+		// Constrain output to DeliveriesSchema, if the server supports it.
+		ResponseFormat: openAIResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: openAIJSONSchema{Name: "deliveries", Schema: DeliveriesSchema},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	url := strings.TrimSuffix(p.BaseURL, "/") + "/chat/completions"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.APIKey)
+	}
+
+	res, err := p.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("OpenAI API error for %s: %w", filePath, err)
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("OpenAI API error for %s: %w", filePath, err)
+	}
+
+	// Keep the raw body, unless we find the model's output.
+	response := &Response{Text: string(resBody), Model: p.Model}
+
+	var data openAIResponse
+	if err := json.Unmarshal(resBody, &data); err != nil {
+		return response, fmt.Errorf("OpenAI API error for %s: HTTP %d: %w", filePath, res.StatusCode, err)
+	}
+	if data.Error != nil {
+		return response, fmt.Errorf("OpenAI API error for %s: HTTP %d: %s", filePath, res.StatusCode, data.Error.Message)
+	}
+	if res.StatusCode != http.StatusOK {
+		return response, fmt.Errorf("OpenAI API error for %s: HTTP %d", filePath, res.StatusCode)
+	}
+	if len(data.Choices) == 0 {
+		return response, fmt.Errorf("OpenAI API returned no choices for %s", filePath)
+	}
+
+	response.Text = data.Choices[0].Message.Content
+	response.InputTokens = data.Usage.PromptTokens
+	response.OutputTokens = data.Usage.CompletionTokens
+	if data.Model != "" {
+		response.Model = data.Model
+	}
+	return response, nil
+}
@@ -18,13 +18,28 @@ package parser
 
 import (
 	"context"
-	"encoding/base64"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"maps"
+	"net/http"
 	"os"
+	"slices"
 
-	"github.com/anthropics/anthropic-sdk-go"
+	"git.cypr.io/oz/aguaxaca/collector"
 )
 
+// DefaultPrompt asks for deliveries as a JSON document, matching
+// DeliveriesSchema.
+const DefaultPrompt = `Perform OCR on this image and extract the water deliveries it announces: their date, schedule (like "matutino" or "nocturno"), location type (like COLONIA or FRACCIONAMIENTOS, but always in singular form and downcased), and location name.
+
+
+Report every delivery with its "date", "schedule", "location_type", and "location_name". For the date, use this format "YYYY-MM-DD" (for example "2025-03-14" for "14 de marzo de 2025"). Only use "notes" for remarks about a single delivery.
+
+
+Output a single JSON object, like {"deliveries": [{"date": "2025-03-14", "schedule": "matutino", "location_type": "colonia", "location_name": "Libertad"}]}. Do not include more details about what the image is about, or other helpful text.`
+
+// DefaultCSVPrompt is the legacy prompt, asking for CSV output.
 const DefaultCSVPrompt = `Perform OCR on this image and extract the schedules (like "matutino" or "nocturno"), list of locations, and location types (like COLONIA or FRACCIONAMIENTOS, but always in singular form and downcased) from the text content.
 
 
@@ -33,29 +48,36 @@ You will output the information using the CSV format, with the following columns
 
 Do not include more details about what the image is about, or other helpful text.`
 
-// ParseFileWithPrompt queries Anthropic with a file attachment, prompting as indicated, and returns the resulting text.
-func ParseFileWithPrompt(ctx context.Context, filePath string, prompt string) (string, error) {
+// Response is the raw output of a parser, with some metadata.
+type Response struct {
+	Text         string
+	Model        string
+	InputTokens  int64
+	OutputTokens int64
+}
+
+// Parser extracts text from an image file, as instructed by a prompt.
+// On errors, the response may still be returned (when the API replied).
+type Parser interface {
+	ParseFile(ctx context.Context, filePath string, prompt string) (*Response, error)
+}
+
+// PromptHash identifies a prompt's version.
+func PromptHash(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:8])
+}
+
+// readImage returns the content of an image file, and its media type
+// (JPEG, PNG or WebP).
+func readImage(filePath string) ([]byte, string, error) {
 	file, err := os.ReadFile(filePath)
 	if err != nil {
-		return "", fmt.Errorf("can't read file %s: %w", filePath, err)
+		return nil, "", fmt.Errorf("can't read file %s: %w", filePath, err)
 	}
-	encodedData := base64.StdEncoding.EncodeToString(file)
-
-	// API key is set with: os.LookupEnv("ANTHROPIC_API_KEY")
-	client := anthropic.NewClient()
-	res, err := client.Messages.New(ctx, anthropic.MessageNewParams{
-		MaxTokens: 20000,
-		Model:     anthropic.ModelClaude4Sonnet20250514,
-		Messages: []anthropic.MessageParam{
-			anthropic.NewUserMessage(
-				anthropic.NewTextBlock(prompt),
-				anthropic.NewImageBlockBase64("image/jpeg", encodedData),
-			),
-		},
-	})
-	if err != nil {
-		return "", fmt.Errorf("Anthropic error for %s: %w", filePath, err)
+	mimeType := http.DetectContentType(file)
+	if !slices.Contains(slices.Collect(maps.Values(collector.ImageTypes)), mimeType) {
+		return nil, "", fmt.Errorf("unsupported image %s: %s", filePath, mimeType)
 	}
-
-	return res.Content[len(res.Content)-1].Text, nil
+	return file, mimeType, nil
 }
@@ -0,0 +1,58 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadImage(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{"png", "\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR", "image/png", false},
+		{"jpeg", "\xff\xd8\xff\xe0\x00\x10JFIF\x00", "image/jpeg", false},
+		{"webp", "RIFF\x00\x00\x00\x00WEBPVP8 ", "image/webp", false},
+		{"gif", "GIF89a\x01\x00\x01\x00", "", true},
+		{"text", "not an image", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "notice.jpg")
+			if err := os.WriteFile(filePath, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, got, err := readImage(filePath)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readImage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), filePath) {
+				t.Errorf("readImage() error = %v, want the file's path", err)
+			}
+			if got != tt.want {
+				t.Errorf("readImage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
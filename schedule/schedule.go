@@ -0,0 +1,194 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package schedule reads the schedules of water deliveries, as written
+// in public notices ("matutino", "nocturno", ...), into time windows.
+package schedule
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	// Embed time zones, missing from minimal docker images.
+	_ "time/tzdata"
+
+	"git.cypr.io/oz/aguaxaca/gazetteer"
+)
+
+// Kind of schedule.
+type Kind string
+
+const (
+	Matutino           Kind = "matutino"
+	Vespertino         Kind = "vespertino"
+	Nocturno           Kind = "nocturno"
+	MatutinoVespertino Kind = "matutino-vespertino"
+	VespertinoNocturno Kind = "vespertino-nocturno"
+	TodoElDia          Kind = "todo-el-dia"
+	Horario            Kind = "horario" // explicit times only
+	Unknown            Kind = "unknown"
+)
+
+// Kinds are the known kinds of schedules.
+var Kinds = []Kind{Matutino, Vespertino, Nocturno, MatutinoVespertino, VespertinoNocturno, TodoElDia, Horario}
+
+// Location is the time zone of the schedules.
+var Location = mustLoadLocation("America/Mexico_City")
+
+// Vocabulary maps schedules found in notices, folded (see
+// gazetteer.Fold), to their kind.
+var Vocabulary = map[string]Kind{
+	"matutino":                     Matutino,
+	"turno matutino":               Matutino,
+	"manana":                       Matutino,
+	"por la manana":                Matutino,
+	"vespertino":                   Vespertino,
+	"turno vespertino":             Vespertino,
+	"tarde":                        Vespertino,
+	"por la tarde":                 Vespertino,
+	"nocturno":                     Nocturno,
+	"turno nocturno":               Nocturno,
+	"noche":                        Nocturno,
+	"por la noche":                 Nocturno,
+	"matutino vespertino":          MatutinoVespertino,
+	"matutino y vespertino":        MatutinoVespertino,
+	"manana y tarde":               MatutinoVespertino,
+	"diurno":                       MatutinoVespertino,
+	"vespertino nocturno":          VespertinoNocturno,
+	"vespertino y nocturno":        VespertinoNocturno,
+	"tarde y noche":                VespertinoNocturno,
+	"todo el dia":                  TodoElDia,
+	"24 horas":                     TodoElDia,
+	"24 hrs":                       TodoElDia,
+	"matutino vespertino nocturno": TodoElDia,
+}
+
+// Windows are the usual hours of each kind of schedule, as notices
+// rarely give them.
+var Windows = map[Kind]Window{
+	Matutino:           {Start: 6 * 60, End: 12 * 60},
+	Vespertino:         {Start: 12 * 60, End: 18 * 60},
+	Nocturno:           {Start: 18 * 60, End: 6 * 60},
+	MatutinoVespertino: {Start: 6 * 60, End: 18 * 60},
+	VespertinoNocturno: {Start: 12 * 60, End: 6 * 60},
+	TodoElDia:          {Start: 0, End: 24 * 60},
+}
+
+// Explicit times, like "de 6:00 a 14:00 hrs".
+var timesRe = regexp.MustCompile(`(?i)(?:de\s+)?(\d{1,2})(?::(\d{2}))?\s*(?:hrs?\.?|h)?\s*(?:a|-|al)\s*(\d{1,2})(?::(\d{2}))?\s*(?:hrs?\.?|h)?`)
+
+// Clock is a time of day, in minutes after midnight.
+type Clock int
+
+func (c Clock) String() string {
+	return fmt.Sprintf("%02d:%02d", c/60, c%60)
+}
+
+// Window is a range of time, ending on the next day when End is before
+// Start.
+type Window struct {
+	Start Clock
+	End   Clock
+}
+
+// String is the window in words: "entre 06:00 y 18:00".
+func (w Window) String() string {
+	return fmt.Sprintf("entre %s y %s", w.Start, w.End)
+}
+
+// On returns the start, and end times of the window on a date.
+func (w Window) On(date time.Time) (time.Time, time.Time) {
+	y, m, d := date.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, Location)
+	start := day.Add(time.Duration(w.Start) * time.Minute)
+	end := day.Add(time.Duration(w.End) * time.Minute)
+	if w.End <= w.Start {
+		end = end.AddDate(0, 0, 1)
+	}
+	return start, end
+}
+
+// Schedule is the kind, and time window of a delivery. Window is nil
+// when unknown.
+type Schedule struct {
+	Kind   Kind
+	Window *Window
+}
+
+// Parse reads a schedule. Explicit times override the usual hours of
+// its kind.
+func Parse(s string) Schedule {
+	var window *Window
+	if m := timesRe.FindStringSubmatch(s); m != nil {
+		if w, ok := newWindow(m[1], m[2], m[3], m[4]); ok {
+			window = &w
+			s = timesRe.ReplaceAllString(s, " ")
+		}
+	}
+
+	kind, ok := Vocabulary[gazetteer.Fold(s)]
+	switch {
+	case ok && window == nil:
+		w := Windows[kind]
+		window = &w
+	case !ok && window != nil && gazetteer.Fold(s) == "":
+		kind = Horario
+	case !ok:
+		kind = Unknown
+	}
+	return Schedule{Kind: kind, Window: window}
+}
+
+// Known is false for schedules missing from the vocabulary.
+func (s Schedule) Known() bool {
+	return s.Kind != Unknown && s.Kind != ""
+}
+
+func newWindow(startH, startM, endH, endM string) (Window, bool) {
+	start, ok := newClock(startH, startM)
+	if !ok {
+		return Window{}, false
+	}
+	end, ok := newClock(endH, endM)
+	if !ok {
+		return Window{}, false
+	}
+	return Window{Start: start, End: end}, true
+}
+
+func newClock(hours, minutes string) (Clock, bool) {
+	h, err := strconv.Atoi(hours)
+	if err != nil || h > 24 {
+		return 0, false
+	}
+	m := 0
+	if minutes != "" {
+		if m, err = strconv.Atoi(minutes); err != nil || m > 59 {
+			return 0, false
+		}
+	}
+	return Clock(h*60 + m), true
+}
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
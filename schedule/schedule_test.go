@@ -0,0 +1,118 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	window := func(start, end Clock) *Window { return &Window{Start: start, End: end} }
+
+	tests := []struct {
+		schedule string
+		want     Schedule
+	}{
+		// Vocabulary, with the usual hours.
+		{"matutino", Schedule{Matutino, window(6*60, 12*60)}},
+		{"Turno Matutino", Schedule{Matutino, window(6*60, 12*60)}},
+		{"por la mañana", Schedule{Matutino, window(6*60, 12*60)}},
+		{"Vespertino", Schedule{Vespertino, window(12*60, 18*60)}},
+		{"nocturno", Schedule{Nocturno, window(18*60, 6*60)}},
+		{"matutino y vespertino", Schedule{MatutinoVespertino, window(6*60, 18*60)}},
+		{"Matutino-Vespertino", Schedule{MatutinoVespertino, window(6*60, 18*60)}},
+		{"tarde y noche", Schedule{VespertinoNocturno, window(12*60, 6*60)}},
+		{"Todo el día", Schedule{TodoElDia, window(0, 24*60)}},
+		{"24 horas", Schedule{TodoElDia, window(0, 24*60)}},
+
+		// Explicit times override the usual hours.
+		{"matutino de 7:00 a 13:00 hrs", Schedule{Matutino, window(7*60, 13*60)}},
+		{"nocturno (20 a 4 h)", Schedule{Nocturno, window(20*60, 4*60)}},
+		{"de 6:30 a 14:45 hrs.", Schedule{Horario, window(6*60+30, 14*60+45)}},
+		{"6-14", Schedule{Horario, window(6*60, 14*60)}},
+
+		// Unknown schedules.
+		{"madrugada", Schedule{Unknown, nil}},
+		{"", Schedule{Unknown, nil}},
+		{"de 25:00 a 3:00", Schedule{Unknown, nil}},
+		{"madrugada de 2 a 5", Schedule{Unknown, window(2*60, 5*60)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.schedule, func(t *testing.T) {
+			got := Parse(tt.schedule)
+			if got.Kind != tt.want.Kind ||
+				(got.Window == nil) != (tt.want.Window == nil) ||
+				(got.Window != nil && *got.Window != *tt.want.Window) {
+				t.Errorf("Parse(%q) = %v %v, want %v %v", tt.schedule, got.Kind, got.Window, tt.want.Kind, tt.want.Window)
+			}
+			if got.Known() != (tt.want.Kind != Unknown) {
+				t.Errorf("Parse(%q).Known() = %t", tt.schedule, got.Known())
+			}
+		})
+	}
+}
+
+func TestWindowOn(t *testing.T) {
+	day := time.Date(2025, 7, 21, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		window     Window
+		date       time.Time
+		start, end string // in UTC
+	}{
+		{"matutino", Windows[Matutino], day, "2025-07-21T12:00:00Z", "2025-07-21T18:00:00Z"},
+		{"vespertino", Windows[Vespertino], day, "2025-07-21T18:00:00Z", "2025-07-22T00:00:00Z"},
+		{"nocturno ends the next day", Windows[Nocturno], day, "2025-07-22T00:00:00Z", "2025-07-22T12:00:00Z"},
+		{"todo el dia", Windows[TodoElDia], day, "2025-07-21T06:00:00Z", "2025-07-22T06:00:00Z"},
+		{"minutes", Window{Start: 6*60 + 30, End: 14*60 + 45}, day, "2025-07-21T12:30:00Z", "2025-07-21T20:45:00Z"},
+		{"same start, and end", Window{Start: 8 * 60, End: 8 * 60}, day, "2025-07-21T14:00:00Z", "2025-07-22T14:00:00Z"},
+
+		// The date is the calendar date of its own time zone.
+		{"late UTC date", Windows[Matutino], day.Add(23 * time.Hour), "2025-07-21T12:00:00Z", "2025-07-21T18:00:00Z"},
+
+		// Mexico City observed daylight saving time until 2022.
+		{"daylight saving time", Windows[Matutino], time.Date(2022, 7, 1, 0, 0, 0, 0, time.UTC), "2022-07-01T11:00:00Z", "2022-07-01T17:00:00Z"},
+		{"winter time", Windows[Matutino], time.Date(2022, 1, 10, 0, 0, 0, 0, time.UTC), "2022-01-10T12:00:00Z", "2022-01-10T18:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := tt.window.On(tt.date)
+			if start.Location() != Location {
+				t.Errorf("On(%v) start in %v, want %v", tt.date, start.Location(), Location)
+			}
+			if got := start.UTC().Format(time.RFC3339); got != tt.start {
+				t.Errorf("On(%v) start = %s, want %s", tt.date, got, tt.start)
+			}
+			if got := end.UTC().Format(time.RFC3339); got != tt.end {
+				t.Errorf("On(%v) end = %s, want %s", tt.date, got, tt.end)
+			}
+		})
+	}
+}
+
+func TestWindowString(t *testing.T) {
+	if got, want := Windows[Nocturno].String(), "entre 18:00 y 06:00"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if got, want := (Window{Start: 6*60 + 5, End: 24 * 60}).String(), "entre 06:05 y 24:00"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
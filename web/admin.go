@@ -0,0 +1,217 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"git.cypr.io/oz/aguaxaca/parser"
+)
+
+// AdminImportsLimit is the number of imports listed on /admin/imports.
+const AdminImportsLimit = 100
+
+// mountAdmin adds the review pages under /admin, protected with HTTP
+// basic auth. They are disabled unless an admin password is set.
+func (s *Server) mountAdmin(r chi.Router) {
+	user, password := s.app.Config.AdminUser, s.app.Config.AdminPassword
+	if password == "" {
+		s.app.Logger.Info("admin pages disabled, set AGUAXACA_ADMIN_PASSWORD to enable them")
+		return
+	}
+
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(middleware.BasicAuth("aguaxaca", map[string]string{user: password}))
+		// Browsers send basic auth credentials with cross-site requests.
+		r.Use(http.NewCrossOriginProtection().Handler)
+
+		r.Get("/imports", s.AdminImportsHandler)
+		r.Get("/imports/{id}", s.AdminImportHandler)
+		r.Get("/imports/{id}/image", s.AdminImageHandler)
+		r.Post("/imports/{id}/deliveries", s.AdminAddDeliveryHandler)
+		r.Post("/imports/{id}/revive", s.AdminReviveImportHandler)
+		r.Post("/deliveries/{id}", s.AdminEditDeliveryHandler)
+		r.Post("/deliveries/{id}/approve", s.AdminApproveDeliveryHandler)
+		r.Post("/deliveries/{id}/delete", s.AdminDeleteDeliveryHandler)
+		r.Post("/quarantine/{id}/approve", s.AdminApproveQuarantineHandler)
+		r.Post("/quarantine/{id}/delete", s.AdminDeleteQuarantineHandler)
+	})
+}
+
+func (s *Server) AdminImportsHandler(w http.ResponseWriter, r *http.Request) {
+	review := s.app.NewReview()
+	imports, err := review.Imports(AdminImportsLimit)
+	if err != nil {
+		s.app.Logger.Error("failed to list imports", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	dead, err := review.DeadImports()
+	if err != nil {
+		s.app.Logger.Error("failed to list dead imports", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	s.render(w, "admin_imports.html", map[string]any{"Imports": imports, "Dead": dead})
+}
+
+func (s *Server) AdminImportHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r)
+	if !ok {
+		return
+	}
+	review, err := s.app.NewReview().Import(id)
+	if err != nil {
+		s.app.Logger.Debug("import not found", "import", id, "error", err)
+		http.NotFound(w, r)
+		return
+	}
+	s.render(w, "admin_import.html", map[string]any{
+		"Review": review,
+		"Error":  r.URL.Query().Get("error"),
+	})
+}
+
+// AdminImageHandler serves the image of an import.
+func (s *Server) AdminImageHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r)
+	if !ok {
+		return
+	}
+	review, err := s.app.NewReview().Import(id)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeFile(w, r, review.Import.FilePath)
+}
+
+func (s *Server) AdminAddDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r)
+	if !ok {
+		return
+	}
+	_, err := s.app.NewReview().AddDelivery(actor(r), id, deliveryForm(r))
+	s.redirectToImport(w, r, id, err)
+}
+
+func (s *Server) AdminReviveImportHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r)
+	if !ok {
+		return
+	}
+	_, err := s.app.NewReview().ReviveImport(actor(r), id)
+	s.redirectToImport(w, r, id, err)
+}
+
+func (s *Server) AdminEditDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r)
+	if !ok {
+		return
+	}
+	_, err := s.app.NewReview().UpdateDelivery(actor(r), id, deliveryForm(r))
+	s.redirectToImport(w, r, formImportID(r), err)
+}
+
+func (s *Server) AdminApproveDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r)
+	if !ok {
+		return
+	}
+	err := s.app.NewReview().ApproveDelivery(actor(r), id)
+	s.redirectToImport(w, r, formImportID(r), err)
+}
+
+func (s *Server) AdminDeleteDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r)
+	if !ok {
+		return
+	}
+	err := s.app.NewReview().DeleteDelivery(actor(r), id)
+	s.redirectToImport(w, r, formImportID(r), err)
+}
+
+func (s *Server) AdminApproveQuarantineHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r)
+	if !ok {
+		return
+	}
+	_, err := s.app.NewQuarantine().Approve(actor(r), id, deliveryForm(r))
+	s.redirectToImport(w, r, formImportID(r), err)
+}
+
+func (s *Server) AdminDeleteQuarantineHandler(w http.ResponseWriter, r *http.Request) {
+	id, ok := idParam(w, r)
+	if !ok {
+		return
+	}
+	err := s.app.NewQuarantine().Delete(actor(r), id)
+	s.redirectToImport(w, r, formImportID(r), err)
+}
+
+// redirectToImport goes back to an import's review page after a form
+// submission, with the error if any.
+func (s *Server) redirectToImport(w http.ResponseWriter, r *http.Request, importID int64, err error) {
+	target := "/admin/imports"
+	if importID != 0 {
+		target = fmt.Sprintf("/admin/imports/%d", importID)
+	}
+	if err != nil {
+		s.app.Logger.Warn("review error", "actor", actor(r), "path", r.URL.Path, "error", err)
+		target += "?error=" + url.QueryEscape(err.Error())
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+// idParam reads the {id} URL parameter, or responds with 404.
+func idParam(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return 0, false
+	}
+	return id, true
+}
+
+// formImportID is the import to go back to, after a form submission.
+func formImportID(r *http.Request) int64 {
+	id, _ := strconv.ParseInt(r.PostFormValue("import"), 10, 64)
+	return id
+}
+
+// deliveryForm reads delivery fields from a form.
+func deliveryForm(r *http.Request) parser.Delivery {
+	return parser.Delivery{
+		Date:         r.PostFormValue("date"),
+		Schedule:     r.PostFormValue("schedule"),
+		LocationType: r.PostFormValue("location_type"),
+		LocationName: r.PostFormValue("location_name"),
+		Notes:        r.PostFormValue("notes"),
+	}
+}
+
+// actor is the reviewer's login, for the audit log.
+func actor(r *http.Request) string {
+	user, _, _ := r.BasicAuth()
+	return "web:" + user
+}
@@ -0,0 +1,424 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package web
+
+import (
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"git.cypr.io/oz/aguaxaca/app"
+	"git.cypr.io/oz/aguaxaca/app/db"
+	"git.cypr.io/oz/aguaxaca/gazetteer"
+	"git.cypr.io/oz/aguaxaca/schedule"
+)
+
+// OpenAPISpec documents the API, see /api/openapi.json.
+//
+//go:embed openapi.json
+var OpenAPISpec []byte
+
+// Pagination of /api/v1/deliveries.
+const (
+	APIDefaultLimit = 50
+	APIMaxLimit     = 500
+)
+
+// APIDefaultDays is how far back /api/v1/deliveries looks, without a
+// "from" date.
+const APIDefaultDays = 7
+
+// maxDate is the "to" date of /api/v1/deliveries, when not given.
+var maxDate = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// mountAPI adds the read-only JSON API under /api/v1, and its OpenAPI
+// document. Keep openapi.json in sync with the routes, and types below.
+func (s *Server) mountAPI(r chi.Router) {
+	r.Get("/api/openapi.json", s.OpenAPIHandler)
+
+	r.Route("/api/v1", func(r chi.Router) {
+		r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+			s.writeAPIError(w, http.StatusNotFound, "not found")
+		})
+		r.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+			s.writeAPIError(w, http.StatusMethodNotAllowed, "method not allowed")
+		})
+
+		r.Get("/deliveries", s.APIDeliveriesHandler)
+		r.Get("/deliveries/{id}", s.APIDeliveryHandler)
+		r.Get("/locations", s.APILocationsHandler)
+		r.Get("/imports/latest", s.APILatestImportHandler)
+	})
+}
+
+// APIDelivery is a delivery, as exposed by the API.
+type APIDelivery struct {
+	ID           int64      `json:"id"`
+	Date         string     `json:"date"`
+	Schedule     string     `json:"schedule"`
+	ScheduleKind string     `json:"schedule_kind"`
+	Window       *APIWindow `json:"window"`
+	LocationType string     `json:"location_type"`
+	LocationName string     `json:"location_name"`
+	Base         string     `json:"base"`
+	Sectors      []string   `json:"sectors"`
+	Section      string     `json:"section"`
+	Orientation  string     `json:"orientation"`
+	LocationID   *int64     `json:"location_id"`
+	Notes        string     `json:"notes"`
+	ImportID     *int64     `json:"import_id"`
+	Approved     bool       `json:"approved"`
+}
+
+// APIWindow is when a delivery starts, and ends.
+type APIWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// APIPagination describes a page of results. NextOffset is nil on the
+// last page.
+type APIPagination struct {
+	Limit      int64  `json:"limit"`
+	Offset     int64  `json:"offset"`
+	NextOffset *int64 `json:"next_offset"`
+}
+
+// APIDeliveries is a page of deliveries.
+type APIDeliveries struct {
+	Deliveries []APIDelivery `json:"deliveries"`
+	Pagination APIPagination `json:"pagination"`
+}
+
+// APILocation is a canonical location, with its known names.
+type APILocation struct {
+	ID           int64    `json:"id"`
+	Slug         string   `json:"slug"`
+	Name         string   `json:"name"`
+	LocationType string   `json:"location_type"`
+	Aliases      []string `json:"aliases"`
+}
+
+// APILocations lists locations.
+type APILocations struct {
+	Locations []APILocation `json:"locations"`
+}
+
+// APIImport is the state of an import.
+type APIImport struct {
+	ID          int64      `json:"id"`
+	Status      string     `json:"status"`
+	Source      string     `json:"source"`
+	PostURL     string     `json:"post_url"`
+	PostedAt    *time.Time `json:"posted_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	FailedAt    *time.Time `json:"failed_at"`
+	Runs        int64      `json:"runs"`
+}
+
+// APIError is the body of error responses.
+type APIError struct {
+	Error APIErrorDetail `json:"error"`
+}
+
+// APIErrorDetail describes an error: its HTTP status, and a message.
+type APIErrorDetail struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+// Statuses of imports.
+const (
+	ImportPending   = "pending"
+	ImportCompleted = "completed"
+	ImportFailed    = "failed"
+	ImportDead      = "dead"
+)
+
+// OpenAPIHandler serves the OpenAPI document of the API.
+func (s *Server) OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(OpenAPISpec)
+}
+
+// APIDeliveriesHandler lists deliveries, filtered by date range
+// ("from", and "to"), name, location type, and kind of schedule, with
+// "limit", and "offset" for pagination.
+func (s *Server) APIDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseDeliveryFilter(r)
+	if err != nil {
+		s.writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Fetch an extra row, to know if there's a next page.
+	limit := filter.Limit
+	filter.Limit++
+	queries := db.New(s.app.DB)
+	var deliveries []db.Delivery
+	if name := r.URL.Query().Get("name"); queryParamToFTS(name) != "" {
+		params := db.FilterDeliveriesByNameParams{
+			FromDate:     filter.FromDate,
+			ToDate:       filter.ToDate,
+			LocationType: filter.LocationType,
+			ScheduleKind: filter.ScheduleKind,
+			Limit:        filter.Limit,
+			Offset:       filter.Offset,
+		}
+		params.LocationName, params.Sectors, params.Section = nameFilter(name)
+		deliveries, err = queries.FilterDeliveriesByName(r.Context(), params)
+	} else {
+		deliveries, err = queries.FilterDeliveries(r.Context(), filter)
+	}
+	if err != nil {
+		s.app.Logger.Error("failed to list deliveries", "error", err)
+		s.writeAPIError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	page := APIDeliveries{
+		Deliveries: []APIDelivery{},
+		Pagination: APIPagination{Limit: limit, Offset: filter.Offset},
+	}
+	if int64(len(deliveries)) > limit {
+		deliveries = deliveries[:limit]
+		next := filter.Offset + limit
+		page.Pagination.NextOffset = &next
+	}
+	for _, d := range deliveries {
+		page.Deliveries = append(page.Deliveries, newAPIDelivery(d))
+	}
+	s.writeJSON(w, http.StatusOK, page)
+}
+
+// parseDeliveryFilter reads the query params of /api/v1/deliveries.
+// Unset filters match anything.
+func parseDeliveryFilter(r *http.Request) (db.FilterDeliveriesParams, error) {
+	query := r.URL.Query()
+	filter := db.FilterDeliveriesParams{
+		FromDate:     db.UnixTime{Time: dateOnly(daysAgo(APIDefaultDays))},
+		ToDate:       db.UnixTime{Time: maxDate},
+		LocationType: "%",
+		ScheduleKind: "%",
+		Limit:        APIDefaultLimit,
+	}
+
+	if from := query.Get("from"); from != "" {
+		date, err := time.Parse(time.DateOnly, from)
+		if err != nil {
+			return filter, fmt.Errorf("invalid 'from' date '%s', expected YYYY-MM-DD", from)
+		}
+		filter.FromDate = db.UnixTime{Time: date}
+	}
+	if to := query.Get("to"); to != "" {
+		date, err := time.Parse(time.DateOnly, to)
+		if err != nil {
+			return filter, fmt.Errorf("invalid 'to' date '%s', expected YYYY-MM-DD", to)
+		}
+		filter.ToDate = db.UnixTime{Time: date}
+	}
+	if filter.ToDate.Time.Before(filter.FromDate.Time) {
+		return filter, fmt.Errorf("'to' date is before 'from' date")
+	}
+
+	if t := query.Get("type"); t != "" {
+		locationType, ok := gazetteer.LocationType(t)
+		if !ok {
+			return filter, fmt.Errorf("unknown location type '%s'", t)
+		}
+		filter.LocationType = locationType
+	}
+	if kind := query.Get("schedule"); kind != "" {
+		if !slices.Contains(schedule.Kinds, schedule.Kind(kind)) {
+			return filter, fmt.Errorf("unknown schedule '%s', expected one of %v", kind, schedule.Kinds)
+		}
+		filter.ScheduleKind = kind
+	}
+
+	var err error
+	if filter.Limit, err = intParam(query.Get("limit"), filter.Limit); err != nil || filter.Limit < 1 || filter.Limit > APIMaxLimit {
+		return filter, fmt.Errorf("invalid 'limit', expected a number between 1 and %d", APIMaxLimit)
+	}
+	if filter.Offset, err = intParam(query.Get("offset"), 0); err != nil || filter.Offset < 0 {
+		return filter, fmt.Errorf("invalid 'offset', expected a positive number")
+	}
+	return filter, nil
+}
+
+// APIDeliveryHandler returns a single delivery.
+func (s *Server) APIDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		s.writeAPIError(w, http.StatusBadRequest, "invalid delivery ID")
+		return
+	}
+
+	delivery, err := db.New(s.app.DB).GetDelivery(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		s.writeAPIError(w, http.StatusNotFound, "delivery not found")
+		return
+	}
+	if err != nil {
+		s.app.Logger.Error("failed to get delivery", "id", id, "error", err)
+		s.writeAPIError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, newAPIDelivery(delivery))
+}
+
+// APILocationsHandler lists the canonical locations, and their aliases.
+func (s *Server) APILocationsHandler(w http.ResponseWriter, r *http.Request) {
+	queries := db.New(s.app.DB)
+	locations, err := queries.ListLocations(r.Context())
+	if err != nil {
+		s.app.Logger.Error("failed to list locations", "error", err)
+		s.writeAPIError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	aliases, err := queries.ListLocationAliases(r.Context())
+	if err != nil {
+		s.app.Logger.Error("failed to list location aliases", "error", err)
+		s.writeAPIError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	byLocation := map[int64][]string{}
+	for _, alias := range aliases {
+		byLocation[alias.LocationID] = append(byLocation[alias.LocationID], alias.Alias)
+	}
+	list := APILocations{Locations: []APILocation{}}
+	for _, loc := range locations {
+		list.Locations = append(list.Locations, APILocation{
+			ID:           loc.ID,
+			Slug:         loc.Slug,
+			Name:         loc.Name,
+			LocationType: loc.LocationType,
+			Aliases:      append([]string{}, byLocation[loc.ID]...),
+		})
+	}
+	s.writeJSON(w, http.StatusOK, list)
+}
+
+// APILatestImportHandler returns the state of the latest import.
+func (s *Server) APILatestImportHandler(w http.ResponseWriter, r *http.Request) {
+	imports, err := db.New(s.app.DB).ListImports(r.Context(), 1)
+	if err != nil {
+		s.app.Logger.Error("failed to get latest import", "error", err)
+		s.writeAPIError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	if len(imports) == 0 {
+		s.writeAPIError(w, http.StatusNotFound, "no import yet")
+		return
+	}
+	s.writeJSON(w, http.StatusOK, newAPIImport(imports[0]))
+}
+
+func newAPIDelivery(d db.Delivery) APIDelivery {
+	delivery := APIDelivery{
+		ID:           d.ID,
+		Date:         d.Date.Time.Format(time.DateOnly),
+		Schedule:     d.Schedule,
+		ScheduleKind: d.ScheduleKind,
+		LocationType: d.LocationType,
+		LocationName: d.LocationName,
+		Base:         d.LocationBase,
+		Sectors:      []string{},
+		Section:      d.LocationSection,
+		Orientation:  d.LocationOrientation,
+		Notes:        d.Notes,
+		Approved:     d.ApprovedAt != nil,
+	}
+	if d.LocationSectors != "" {
+		delivery.Sectors = strings.Split(d.LocationSectors, ",")
+	}
+	if d.LocationID.Valid {
+		delivery.LocationID = &d.LocationID.Int64
+	}
+	if d.ImportID.Valid {
+		delivery.ImportID = &d.ImportID.Int64
+	}
+	if window := app.DeliverySchedule(d).Window; window != nil {
+		start, end := window.On(d.Date.Time)
+		delivery.Window = &APIWindow{Start: start, End: end}
+	}
+	return delivery
+}
+
+func newAPIImport(im db.Import) APIImport {
+	out := APIImport{
+		ID:        im.ID,
+		Status:    ImportPending,
+		Source:    im.Source,
+		PostURL:   im.PostUrl,
+		CreatedAt: im.CreatedAt.Time,
+		Runs:      im.Runs.Int64,
+	}
+	if im.PostedAt != nil {
+		out.PostedAt = &im.PostedAt.Time
+	}
+	if im.FailedAt != nil {
+		out.Status = ImportFailed
+		out.FailedAt = &im.FailedAt.Time
+	}
+	if im.DeadAt != nil {
+		out.Status = ImportDead
+	}
+	if im.CompletedAt != nil {
+		out.Status = ImportCompleted
+		out.CompletedAt = &im.CompletedAt.Time
+	}
+	return out
+}
+
+// writeJSON responds with v, encoded in JSON.
+func (s *Server) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.app.Logger.Error("failed to encode JSON response", "error", err)
+	}
+}
+
+// writeAPIError responds with an APIError.
+func (s *Server) writeAPIError(w http.ResponseWriter, status int, message string) {
+	s.writeJSON(w, status, APIError{Error: APIErrorDetail{Status: status, Message: message}})
+}
+
+// intParam parses an integer query param, or returns def when empty.
+func intParam(param string, def int64) (int64, error) {
+	if param == "" {
+		return def, nil
+	}
+	return strconv.ParseInt(param, 10, 64)
+}
+
+// dateOnly drops the time of t, keeping its date.
+func dateOnly(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
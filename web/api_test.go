@@ -0,0 +1,491 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package web
+
+// Contract tests: the routes, parameters, and responses of the API must
+// match its OpenAPI document (openapi.json).
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"git.cypr.io/oz/aguaxaca/app"
+	"git.cypr.io/oz/aguaxaca/app/db"
+	"git.cypr.io/oz/aguaxaca/parser"
+)
+
+// openAPI is the subset of an OpenAPI 3.1 document checked by tests.
+type openAPI struct {
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components struct {
+		Schemas   map[string]*schema   `json:"schemas"`
+		Responses map[string]*response `json:"responses"`
+	} `json:"components"`
+}
+
+type operation struct {
+	Parameters []parameter          `json:"parameters"`
+	Responses  map[string]*response `json:"responses"`
+}
+
+type parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *schema `json:"schema"`
+	Example  any     `json:"example"`
+}
+
+type response struct {
+	Ref     string `json:"$ref"`
+	Content map[string]struct {
+		Schema *schema `json:"schema"`
+	} `json:"content"`
+}
+
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 any                `json:"type"` // a type, or a list of types
+	Format               string             `json:"format"`
+	Enum                 []any              `json:"enum"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *schema            `json:"items"`
+	OneOf                []*schema          `json:"oneOf"`
+}
+
+func loadSpec(t *testing.T) *openAPI {
+	t.Helper()
+	spec := &openAPI{}
+	if err := json.Unmarshal(OpenAPISpec, spec); err != nil {
+		t.Fatalf("invalid openapi.json: %v", err)
+	}
+	return spec
+}
+
+// newTestServer runs the app with an empty DB.
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	a := app.NewApp(t.Context())
+	a.Logger = slog.New(slog.DiscardHandler)
+	a.Config.DataDir = t.TempDir()
+	if err := a.Init(); err != nil {
+		t.Fatalf("app init: %v", err)
+	}
+	t.Cleanup(func() { a.DB.Close() })
+	return NewServer(a)
+}
+
+// addTestData imports two deliveries, dated today: one matching a
+// location, and one without location.
+func addTestData(t *testing.T, s *Server) {
+	t.Helper()
+	ctx := t.Context()
+	queries := db.New(s.app.DB)
+	now := time.Now().UTC()
+
+	im, err := queries.CreateImport(ctx, db.CreateImportParams{
+		FilePath: "test.png",
+		FileHash: 1,
+		Source:   "test",
+		PostUrl:  "https://example.com/1",
+		PostedAt: &db.UnixTime{Time: now},
+	})
+	if err != nil {
+		t.Fatalf("CreateImport: %v", err)
+	}
+	if _, err := s.app.NewLocations().Add("Libertad", "colonia"); err != nil {
+		t.Fatalf("add location: %v", err)
+	}
+
+	date := now.Format(time.DateOnly)
+	response := fmt.Sprintf(`{"deliveries": [
+		{"date": "%s", "schedule": "matutino", "location_type": "colonia", "location_name": "Libertad"},
+		{"date": "%s", "schedule": "nocturno", "location_type": "ejido", "location_name": "Guadalupe Victoria (sector 1, 2ª sección Oeste)"}
+	]}`, date, date)
+	if err := s.app.NewAnalyzer(parser.NewFakeParser("")).ImportData(&im, response); err != nil {
+		t.Fatalf("ImportData: %v", err)
+	}
+	if err := queries.CompleteImport(ctx, im.ID); err != nil {
+		t.Fatalf("CompleteImport: %v", err)
+	}
+}
+
+// apiRoutes lists the "METHOD /path" of the API routes registered in
+// NewHandler. Other routes serve HTML pages.
+func apiRoutes(t *testing.T, s *Server) []string {
+	t.Helper()
+	routes := []string{}
+	walk := func(method string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if strings.HasPrefix(route, "/api/") {
+			routes = append(routes, method+" "+route)
+		}
+		return nil
+	}
+	if err := chi.Walk(s.NewHandler().(chi.Routes), walk); err != nil {
+		t.Fatalf("walk routes: %v", err)
+	}
+	slices.Sort(routes)
+	return routes
+}
+
+// specRoutes lists the "METHOD /path" of the spec's operations.
+func specRoutes(spec *openAPI) []string {
+	routes := []string{}
+	for path, item := range spec.Paths {
+		for method := range item {
+			routes = append(routes, strings.ToUpper(method)+" "+path)
+		}
+	}
+	slices.Sort(routes)
+	return routes
+}
+
+func TestOpenAPIRoutes(t *testing.T) {
+	spec := loadSpec(t)
+	registered := apiRoutes(t, newTestServer(t))
+	documented := specRoutes(spec)
+
+	for _, route := range registered {
+		if !slices.Contains(documented, route) {
+			t.Errorf("route %s is missing from openapi.json", route)
+		}
+	}
+	for _, route := range documented {
+		if !slices.Contains(registered, route) {
+			t.Errorf("operation %s of openapi.json is not registered", route)
+		}
+	}
+}
+
+var pathParamRe = regexp.MustCompile(`\{(\w+)\}`)
+
+func TestOpenAPIParameters(t *testing.T) {
+	spec := loadSpec(t)
+	for path, item := range spec.Paths {
+		for method, op := range item {
+			// Path params, and their declaration must match.
+			inPath := []string{}
+			for _, m := range pathParamRe.FindAllStringSubmatch(path, -1) {
+				inPath = append(inPath, m[1])
+			}
+			declared := []string{}
+			for _, p := range op.Parameters {
+				if p.Schema == nil {
+					t.Errorf("%s %s: parameter %s has no schema", method, path, p.Name)
+				}
+				if p.Example == nil {
+					t.Errorf("%s %s: parameter %s has no example", method, path, p.Name)
+				}
+				switch p.In {
+				case "path":
+					if !p.Required {
+						t.Errorf("%s %s: path parameter %s must be required", method, path, p.Name)
+					}
+					declared = append(declared, p.Name)
+				case "query":
+				default:
+					t.Errorf("%s %s: parameter %s is in %s", method, path, p.Name, p.In)
+				}
+			}
+			slices.Sort(inPath)
+			slices.Sort(declared)
+			if !slices.Equal(inPath, declared) {
+				t.Errorf("%s %s: path parameters %v, declared %v", method, path, inPath, declared)
+			}
+		}
+	}
+}
+
+func TestOpenAPIResponses(t *testing.T) {
+	spec := loadSpec(t)
+	s := newTestServer(t)
+	addTestData(t, s)
+	handler := s.NewHandler()
+
+	for path, item := range spec.Paths {
+		for method, op := range item {
+			// A request with every example parameter succeeds.
+			target := requestPath(path, op.Parameters, nil)
+			t.Run(method+" "+target, func(t *testing.T) {
+				checkResponse(t, spec, handler, strings.ToUpper(method), target, op, http.StatusOK)
+			})
+
+			// Invalid parameters are rejected.
+			for _, p := range op.Parameters {
+				for _, value := range invalidValues(spec, p.Schema) {
+					target := requestPath(path, op.Parameters, map[string]string{p.Name: value})
+					t.Run(method+" "+target, func(t *testing.T) {
+						checkResponse(t, spec, handler, strings.ToUpper(method), target, op, http.StatusBadRequest)
+					})
+				}
+			}
+
+			// Unknown resources are not found.
+			if op.Responses["404"] != nil && strings.Contains(path, "{") {
+				target := requestPath(path, op.Parameters, map[string]string{"id": "999999"})
+				t.Run(method+" "+target, func(t *testing.T) {
+					checkResponse(t, spec, handler, strings.ToUpper(method), target, op, http.StatusNotFound)
+				})
+			}
+		}
+	}
+}
+
+func TestOpenAPIEmptyDB(t *testing.T) {
+	spec := loadSpec(t)
+	handler := newTestServer(t).NewHandler()
+
+	// Without data, lists are empty, and single resources not found.
+	for path, item := range spec.Paths {
+		for method, op := range item {
+			target := requestPath(path, op.Parameters, nil)
+			want := http.StatusOK
+			if op.Responses["404"] != nil {
+				want = http.StatusNotFound
+			}
+			t.Run(method+" "+target, func(t *testing.T) {
+				checkResponse(t, spec, handler, strings.ToUpper(method), target, op, want)
+			})
+		}
+	}
+}
+
+func TestAPIPagination(t *testing.T) {
+	s := newTestServer(t)
+	addTestData(t, s)
+	handler := s.NewHandler()
+
+	ids := []float64{}
+	target := "/api/v1/deliveries?limit=1"
+	for range 3 {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		var page APIDeliveries
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatalf("GET %s: %v", target, err)
+		}
+		for _, d := range page.Deliveries {
+			ids = append(ids, float64(d.ID))
+		}
+		if page.Pagination.NextOffset == nil {
+			break
+		}
+		target = "/api/v1/deliveries?limit=1&offset=" + strconv.FormatInt(*page.Pagination.NextOffset, 10)
+	}
+	if len(ids) != 2 || ids[0] == ids[1] {
+		t.Errorf("pages listed deliveries %v, want 2 different deliveries", ids)
+	}
+}
+
+// requestPath builds a request URL from the parameters' examples, and
+// values replacing some of them.
+func requestPath(path string, params []parameter, values map[string]string) string {
+	query := url.Values{}
+	for _, p := range params {
+		value, ok := values[p.Name]
+		if !ok {
+			value = fmt.Sprint(p.Example)
+		}
+		switch p.In {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+p.Name+"}", url.PathEscape(value))
+		case "query":
+			query.Set(p.Name, value)
+		}
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return path
+}
+
+// invalidValues returns values that break the constraints of a
+// parameter's schema. Free text has none.
+func invalidValues(spec *openAPI, s *schema) []string {
+	s = resolve(spec, s)
+	values := []string{}
+	if len(s.Enum) > 0 || s.Format != "" || s.Type == "integer" {
+		values = append(values, "invalid!")
+	}
+	if s.Minimum != nil {
+		values = append(values, strconv.FormatFloat(*s.Minimum-1, 'f', -1, 64))
+	}
+	if s.Maximum != nil {
+		values = append(values, strconv.FormatFloat(*s.Maximum+1, 'f', -1, 64))
+	}
+	return values
+}
+
+// checkResponse sends a request, and checks that the response has the
+// wanted status, and matches its documented schema.
+func checkResponse(t *testing.T, spec *openAPI, handler http.Handler, method string, target string, op *operation, want int) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+
+	if rec.Code != want {
+		t.Fatalf("status %d, want %d: %s", rec.Code, want, rec.Body)
+	}
+	resp := op.Responses[strconv.Itoa(rec.Code)]
+	if resp == nil {
+		t.Fatalf("status %d is not documented", rec.Code)
+	}
+	if resp.Ref != "" {
+		resp = spec.Components.Responses[strings.TrimPrefix(resp.Ref, "#/components/responses/")]
+	}
+
+	contentType := rec.Header().Get("Content-Type")
+	if !strings.HasPrefix(contentType, "application/json") {
+		t.Fatalf("content type %q, want application/json", contentType)
+	}
+	var body any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	for _, err := range validate(spec, resp.Content["application/json"].Schema, body, "body") {
+		t.Error(err)
+	}
+}
+
+// resolve follows a schema's $ref.
+func resolve(spec *openAPI, s *schema) *schema {
+	for s != nil && s.Ref != "" {
+		s = spec.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+// validate checks a decoded JSON value against a schema, and returns
+// every mismatch.
+func validate(spec *openAPI, s *schema, value any, at string) []error {
+	s = resolve(spec, s)
+	if s == nil {
+		return []error{fmt.Errorf("%s: unknown schema", at)}
+	}
+
+	if len(s.OneOf) > 0 {
+		matches := 0
+		for _, alt := range s.OneOf {
+			if len(validate(spec, alt, value, at)) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return []error{fmt.Errorf("%s: %v matches %d schemas of oneOf, want 1", at, value, matches)}
+		}
+		return nil
+	}
+
+	if !hasType(s, value) {
+		return []error{fmt.Errorf("%s: %v (%T) does not match type %v", at, value, value, s.Type)}
+	}
+	if len(s.Enum) > 0 && !slices.Contains(s.Enum, value) {
+		return []error{fmt.Errorf("%s: %v is not one of %v", at, value, s.Enum)}
+	}
+
+	errs := []error{}
+	switch v := value.(type) {
+	case string:
+		layout := map[string]string{"date": time.DateOnly, "date-time": time.RFC3339}[s.Format]
+		if _, err := time.Parse(layout, v); layout != "" && err != nil {
+			errs = append(errs, fmt.Errorf("%s: %q is not a %s", at, v, s.Format))
+		}
+	case []any:
+		for i, item := range v {
+			errs = append(errs, validate(spec, s.Items, item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				errs = append(errs, fmt.Errorf("%s: missing property %s", at, name))
+			}
+		}
+		for name, prop := range v {
+			propSchema, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					errs = append(errs, fmt.Errorf("%s: undocumented property %s", at, name))
+				}
+				continue
+			}
+			errs = append(errs, validate(spec, propSchema, prop, at+"."+name)...)
+		}
+	}
+	return errs
+}
+
+// hasType checks the JSON type of a value.
+func hasType(s *schema, value any) bool {
+	types := []string{}
+	switch t := s.Type.(type) {
+	case nil:
+		return true
+	case string:
+		types = append(types, t)
+	case []any:
+		for _, name := range t {
+			types = append(types, fmt.Sprint(name))
+		}
+	}
+
+	for _, name := range types {
+		switch v := value.(type) {
+		case nil:
+			if name == "null" {
+				return true
+			}
+		case bool:
+			if name == "boolean" {
+				return true
+			}
+		case float64:
+			if name == "number" || (name == "integer" && v == math.Trunc(v)) {
+				return true
+			}
+		case string:
+			if name == "string" {
+				return true
+			}
+		case []any:
+			if name == "array" {
+				return true
+			}
+		case map[string]any:
+			if name == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,174 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package web
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"git.cypr.io/oz/aguaxaca/app"
+	"git.cypr.io/oz/aguaxaca/app/db"
+	"git.cypr.io/oz/aguaxaca/schedule"
+)
+
+// CalendarDays is how far back calendars list deliveries.
+const CalendarDays = 90
+
+// iCalendar date-time formats, in UTC, and dates.
+const (
+	icalDateTime = "20060102T150405Z"
+	icalDate     = "20060102"
+)
+
+// LocationCalendarHandler serves the deliveries of a location, as an
+// iCalendar feed.
+func (s *Server) LocationCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	queries := db.New(s.app.DB)
+	location, err := queries.GetLocationBySlug(r.Context(), chi.URLParam(r, "slug"))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		s.app.Logger.Error("failed to get location", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	deliveries, err := queries.ListDeliveriesByLocation(r.Context(), db.ListDeliveriesByLocationParams{
+		LocationID: sql.NullInt64{Int64: location.ID, Valid: true},
+		Date:       db.UnixTime{Time: daysAgo(CalendarDays)},
+	})
+	if err != nil {
+		s.app.Logger.Error("failed to list deliveries", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	s.writeCalendar(w, r, "Agua: "+location.Name, deliveries)
+}
+
+// SearchCalendarHandler serves the deliveries found like on the home
+// page (with the "name" query param), as an iCalendar feed.
+func (s *Server) SearchCalendarHandler(w http.ResponseWriter, r *http.Request) {
+	nameParam := r.URL.Query().Get("name")
+	deliveries, err := findDeliveries(r, s.app.DB, nameParam)
+	if err != nil {
+		s.app.Logger.Error("failed to list deliveries", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	name := "Agua en Oaxaca"
+	if nameParam = strings.TrimSpace(nameParam); nameParam != "" {
+		name = "Agua: " + nameParam
+	}
+	s.writeCalendar(w, r, name, deliveries)
+}
+
+func (s *Server) writeCalendar(w http.ResponseWriter, r *http.Request, name string, deliveries []db.Delivery) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	if _, err := w.Write([]byte(calendar(name, r.Host, deliveries))); err != nil {
+		s.app.Logger.Error("failed to write calendar", "error", err)
+	}
+}
+
+// calendar builds an iCalendar document (RFC 5545), with an event per
+// delivery. Events are identified by their delivery's ID, on host:
+// "delivery-42@agua.example.com". Their sequence number is their
+// delivery's revision: clients replace an event by a later revision.
+func calendar(name string, host string, deliveries []db.Delivery) string {
+	var b strings.Builder
+	line := func(name string, value string) {
+		b.WriteString(foldLine(name + ":" + value))
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//Aguaxaca//Entregas de agua//ES")
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	line("X-WR-CALNAME", escapeText(name))
+	line("X-WR-TIMEZONE", schedule.Location.String())
+	line("REFRESH-INTERVAL;VALUE=DURATION", "PT6H")
+	line("X-PUBLISHED-TTL", "PT6H")
+
+	for _, d := range deliveries {
+		modified := d.CreatedAt.Time
+		if d.UpdatedAt != nil {
+			modified = d.UpdatedAt.Time
+		}
+
+		line("BEGIN", "VEVENT")
+		line("UID", fmt.Sprintf("delivery-%d@%s", d.ID, host))
+		line("SEQUENCE", strconv.FormatInt(d.Revision, 10))
+		line("DTSTAMP", modified.UTC().Format(icalDateTime))
+		line("LAST-MODIFIED", modified.UTC().Format(icalDateTime))
+
+		// Times of the schedule's window, in Oaxaca, or the whole day.
+		description := d.Schedule
+		if window := app.DeliverySchedule(d).Window; window != nil {
+			start, end := window.On(d.Date.Time)
+			line("DTSTART", start.UTC().Format(icalDateTime))
+			line("DTEND", end.UTC().Format(icalDateTime))
+			description += ", " + window.String()
+		} else {
+			line("DTSTART;VALUE=DATE", d.Date.Time.Format(icalDate))
+			line("DTEND;VALUE=DATE", d.Date.Time.AddDate(0, 0, 1).Format(icalDate))
+		}
+		if d.Notes != "" {
+			description += "\n" + d.Notes
+		}
+
+		line("SUMMARY", escapeText("💧 "+d.LocationName))
+		line("DESCRIPTION", escapeText(d.LocationType+" "+d.LocationName+"\n"+description))
+		line("LOCATION", escapeText(d.LocationName+", Oaxaca"))
+		line("TRANSP", "TRANSPARENT")
+		line("END", "VEVENT")
+	}
+
+	line("END", "VCALENDAR")
+	return b.String()
+}
+
+// escapeText escapes a TEXT value.
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// foldLine splits a content line in lines of 75 octets at most, without
+// breaking UTF-8 characters, and terminates it with CRLF.
+func foldLine(s string) string {
+	var b strings.Builder
+	size := 0
+	for _, r := range s {
+		n := len(string(r))
+		if size+n > 75 {
+			b.WriteString("\r\n ")
+			size = 1
+		}
+		b.WriteRune(r)
+		size += n
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
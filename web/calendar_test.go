@@ -0,0 +1,269 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package web
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"git.cypr.io/oz/aguaxaca/app/db"
+	"git.cypr.io/oz/aguaxaca/parser"
+)
+
+func TestFoldLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"short", "SUMMARY:Libertad"},
+		{"75 octets", "DESCRIPTION:" + strings.Repeat("a", 63)},
+		{"76 octets", "DESCRIPTION:" + strings.Repeat("a", 64)},
+		{"very long", "DESCRIPTION:" + strings.Repeat("abcdefghij", 20)},
+		{"multi-byte characters", "SUMMARY:" + strings.Repeat("💧ñ", 30)},
+		{"multi-byte character at the limit", "DESCRIPTION:" + strings.Repeat("a", 62) + "ñ" + "b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := foldLine(tt.line)
+			if !strings.HasSuffix(got, "\r\n") {
+				t.Fatalf("foldLine() = %q, want a CRLF at the end", got)
+			}
+			lines := strings.Split(strings.TrimSuffix(got, "\r\n"), "\r\n")
+			for i, line := range lines {
+				if len(line) > 75 {
+					t.Errorf("line %d has %d octets: %q", i, len(line), line)
+				}
+				if !utf8.ValidString(line) {
+					t.Errorf("line %d splits a character: %q", i, line)
+				}
+				if i > 0 && !strings.HasPrefix(line, " ") {
+					t.Errorf("continuation line %d doesn't start with a space: %q", i, line)
+				}
+			}
+			if len(tt.line) <= 75 && len(lines) != 1 {
+				t.Errorf("line of %d octets was folded in %d lines", len(tt.line), len(lines))
+			}
+			if unfolded := unfold(got); unfolded != tt.line {
+				t.Errorf("unfolded line = %q, want %q", unfolded, tt.line)
+			}
+		})
+	}
+}
+
+func TestEscapeText(t *testing.T) {
+	tests := []struct{ text, want string }{
+		{"Libertad", "Libertad"},
+		{"Jardín (sector 1, 2)", `Jardín (sector 1\, 2)`},
+		{"pipa; temprano", `pipa\; temprano`},
+		{`C:\agua`, `C:\\agua`},
+		{"línea 1\nlínea 2", `línea 1\nlínea 2`},
+		{"línea 1\r\nlínea 2", `línea 1\nlínea 2`},
+		{`\,;`, `\\\,\;`},
+		{"a: b", "a: b"},
+	}
+	for _, tt := range tests {
+		if got := escapeText(tt.text); got != tt.want {
+			t.Errorf("escapeText(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCalendar(t *testing.T) {
+	date := db.UnixTime{Time: time.Date(2025, 7, 21, 0, 0, 0, 0, time.UTC)}
+	created := db.UnixTime{Time: time.Date(2025, 7, 20, 15, 4, 5, 0, time.UTC)}
+	deliveries := []db.Delivery{
+		{
+			ID: 1, Date: date, CreatedAt: created,
+			Schedule: "matutino", ScheduleKind: "matutino",
+			ScheduleStart: sql.NullInt64{Int64: 6 * 60, Valid: true},
+			ScheduleEnd:   sql.NullInt64{Int64: 12 * 60, Valid: true},
+			LocationType:  "colonia", LocationName: "Libertad",
+		},
+		{
+			ID: 2, Date: date, CreatedAt: created,
+			Schedule: "nocturno", ScheduleKind: "nocturno",
+			ScheduleStart: sql.NullInt64{Int64: 18 * 60, Valid: true},
+			ScheduleEnd:   sql.NullInt64{Int64: 6 * 60, Valid: true},
+			LocationType:  "colonia", LocationName: "Jardín (sector 1, 2)",
+			Notes: "pipa; temprano",
+		},
+		{
+			ID: 3, Date: date, CreatedAt: created,
+			Schedule: "madrugada", ScheduleKind: "unknown",
+			LocationType: "barrio", LocationName: "Centro",
+			Revision:  2,
+			UpdatedAt: &db.UnixTime{Time: time.Date(2025, 7, 22, 8, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	doc := calendar("Agua: Libertad", "agua.example.com", deliveries)
+	if !strings.HasPrefix(doc, "BEGIN:VCALENDAR\r\n") || !strings.HasSuffix(doc, "END:VCALENDAR\r\n") {
+		t.Fatalf("calendar() is not a VCALENDAR:\n%s", doc)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(doc, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line has %d octets: %q", len(line), line)
+		}
+	}
+
+	events := calendarEvents(unfold(doc))
+	if len(events) != len(deliveries) {
+		t.Fatalf("got %d events, want %d", len(events), len(deliveries))
+	}
+	want := []map[string]string{
+		// Windowed events, in UTC: Oaxaca is UTC-6.
+		{
+			"UID":           "delivery-1@agua.example.com",
+			"SEQUENCE":      "0",
+			"DTSTAMP":       "20250720T150405Z",
+			"LAST-MODIFIED": "20250720T150405Z",
+			"DTSTART":       "20250721T120000Z",
+			"DTEND":         "20250721T180000Z",
+			"SUMMARY":       "💧 Libertad",
+			"DESCRIPTION":   `colonia Libertad\nmatutino\, entre 06:00 y 12:00`,
+		},
+		{
+			"UID":         "delivery-2@agua.example.com",
+			"SEQUENCE":    "0",
+			"DTSTART":     "20250722T000000Z",
+			"DTEND":       "20250722T120000Z",
+			"SUMMARY":     `💧 Jardín (sector 1\, 2)`,
+			"DESCRIPTION": `colonia Jardín (sector 1\, 2)\nnocturno\, entre 18:00 y 06:00\npipa\; temprano`,
+			"LOCATION":    `Jardín (sector 1\, 2)\, Oaxaca`,
+		},
+		// All-day event, without a window, updated twice.
+		{
+			"UID":                "delivery-3@agua.example.com",
+			"SEQUENCE":           "2",
+			"DTSTAMP":            "20250722T080000Z",
+			"LAST-MODIFIED":      "20250722T080000Z",
+			"DTSTART;VALUE=DATE": "20250721",
+			"DTEND;VALUE=DATE":   "20250722",
+			"DESCRIPTION":        `barrio Centro\nmadrugada`,
+		},
+	}
+	for i, props := range want {
+		for name, value := range props {
+			if got, ok := events[i][name]; !ok || got != value {
+				t.Errorf("event %d: %s = %q, want %q", i, name, got, value)
+			}
+		}
+	}
+	if _, ok := events[2]["DTSTART"]; ok {
+		t.Errorf("all-day event has a DTSTART date-time")
+	}
+}
+
+func TestCalendarReanalysis(t *testing.T) {
+	s := newTestServer(t)
+	queries := db.New(s.app.DB)
+	analyzer := s.app.NewAnalyzer(parser.NewFakeParser(""))
+	if _, err := s.app.NewLocations().Add("Libertad", "colonia"); err != nil {
+		t.Fatalf("add location: %v", err)
+	}
+	im, err := queries.CreateImport(t.Context(), db.CreateImportParams{FilePath: "test.png", FileHash: 1, Source: "test"})
+	if err != nil {
+		t.Fatalf("CreateImport: %v", err)
+	}
+
+	date := time.Now().UTC().Format(time.DateOnly)
+	response := func(notes string) string {
+		return fmt.Sprintf(`{"deliveries": [
+			{"date": "%s", "schedule": "matutino", "location_type": "colonia", "location_name": "Libertad", "notes": "%s"}
+		]}`, date, notes)
+	}
+	event := func() map[string]string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		s.NewHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/colonia/libertad/calendar.ics", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET calendar.ics = %d", rec.Code)
+		}
+		events := calendarEvents(unfold(rec.Body.String()))
+		if len(events) != 1 {
+			t.Fatalf("got %d events, want 1", len(events))
+		}
+		return events[0]
+	}
+
+	if err := analyzer.ImportData(&im, response("")); err != nil {
+		t.Fatalf("ImportData: %v", err)
+	}
+	// Created an hour ago, to tell updates apart.
+	if _, err := s.app.DB.Exec("UPDATE deliveries SET created_at = created_at - 3600"); err != nil {
+		t.Fatalf("backdate deliveries: %v", err)
+	}
+	created := event()
+	if created["SEQUENCE"] != "0" {
+		t.Errorf("new event: SEQUENCE = %q, want 0", created["SEQUENCE"])
+	}
+
+	// Analyzed again, with the same values: nothing changes.
+	if err := analyzer.ImportData(&im, response("")); err != nil {
+		t.Fatalf("ImportData: %v", err)
+	}
+	if same := event(); same["SEQUENCE"] != "0" || same["LAST-MODIFIED"] != created["LAST-MODIFIED"] {
+		t.Errorf("unchanged event: SEQUENCE = %q, LAST-MODIFIED = %q, want 0, %q", same["SEQUENCE"], same["LAST-MODIFIED"], created["LAST-MODIFIED"])
+	}
+
+	// Analyzed again, with notes: the delivery is updated in place.
+	if err := analyzer.ImportData(&im, response("pipa")); err != nil {
+		t.Fatalf("ImportData: %v", err)
+	}
+	updated := event()
+	if updated["UID"] != created["UID"] {
+		t.Errorf("updated event: UID = %q, want %q", updated["UID"], created["UID"])
+	}
+	if updated["SEQUENCE"] != "1" {
+		t.Errorf("updated event: SEQUENCE = %q, want 1", updated["SEQUENCE"])
+	}
+	if updated["LAST-MODIFIED"] <= created["LAST-MODIFIED"] {
+		t.Errorf("updated event: LAST-MODIFIED = %q, want after %q", updated["LAST-MODIFIED"], created["LAST-MODIFIED"])
+	}
+}
+
+// unfold joins folded lines (RFC 5545, section 3.1).
+func unfold(doc string) string {
+	return strings.TrimSuffix(strings.ReplaceAll(doc, "\r\n ", ""), "\r\n")
+}
+
+// calendarEvents returns the properties of each VEVENT of an unfolded
+// document, by name (with parameters).
+func calendarEvents(doc string) []map[string]string {
+	var events []map[string]string
+	var event map[string]string
+	for _, line := range strings.Split(doc, "\r\n") {
+		name, value, _ := strings.Cut(line, ":")
+		switch {
+		case line == "BEGIN:VEVENT":
+			event = map[string]string{}
+		case line == "END:VEVENT":
+			events = append(events, event)
+			event = nil
+		case event != nil:
+			event[name] = value
+		}
+	}
+	return events
+}
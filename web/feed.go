@@ -0,0 +1,232 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package web
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"git.cypr.io/oz/aguaxaca/app/db"
+)
+
+// FeedTagDate is the date of the tagging entity of the feed's, and
+// entries' IDs (RFC 4151), after the server's host name:
+// "tag:agua.example.com,2025:deliveries/notice/42/2025-07-21".
+const FeedTagDate = "2025"
+
+// Atom documents (RFC 4287), as much as we need.
+type (
+	AtomFeed struct {
+		XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+		ID      string      `xml:"id"`
+		Title   string      `xml:"title"`
+		Updated string      `xml:"updated"`
+		Author  AtomAuthor  `xml:"author"`
+		Links   []AtomLink  `xml:"link"`
+		Entries []AtomEntry `xml:"entry"`
+	}
+
+	AtomAuthor struct {
+		Name string `xml:"name"`
+	}
+
+	AtomLink struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr,omitempty"`
+		Type string `xml:"type,attr,omitempty"`
+	}
+
+	AtomEntry struct {
+		ID      string      `xml:"id"`
+		Title   string      `xml:"title"`
+		Updated string      `xml:"updated"`
+		Links   []AtomLink  `xml:"link"`
+		Content AtomContent `xml:"content"`
+	}
+
+	AtomContent struct {
+		Type string `xml:"type,attr"`
+		Body string `xml:",chardata"`
+	}
+)
+
+// FeedHandler serves the deliveries found like on the home page (the
+// latest, or a search with the "name" query param), as an Atom feed with
+// an entry per source notice, and date.
+func (s *Server) FeedHandler(w http.ResponseWriter, r *http.Request) {
+	nameParam := strings.TrimSpace(r.URL.Query().Get("name"))
+	deliveries, err := findDeliveries(r, s.app.DB, nameParam)
+	if err != nil {
+		s.app.Logger.Error("failed to list deliveries", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	feed, err := s.feed(r, nameParam, deliveries)
+	if err != nil {
+		s.app.Logger.Error("failed to build feed", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		s.app.Logger.Error("failed to write feed", "error", err)
+	}
+}
+
+// feedGroup is the deliveries of a notice on a date.
+type feedGroup struct {
+	importID   sql.NullInt64
+	date       time.Time
+	deliveries []db.Delivery
+	updated    time.Time
+}
+
+func (s *Server) feed(r *http.Request, nameParam string, deliveries []db.Delivery) (AtomFeed, error) {
+	base := baseURL(r)
+	page, self := base+"/", base+"/feed.atom"
+	title, feedID := "Entregas de agua en Oaxaca", "tag:"+feedTagAuthority(r)+":deliveries"
+	if queryParamToFTS(nameParam) != "" {
+		query := "?name=" + url.QueryEscape(nameParam)
+		page, self = page+query, self+query
+		title = "Entregas de agua en: " + nameParam
+		feedID += ":" + url.QueryEscape(strings.ToLower(nameParam))
+	}
+
+	feed := AtomFeed{
+		ID:     feedID,
+		Title:  title,
+		Author: AtomAuthor{Name: "Aguaxaca"},
+		Links: []AtomLink{
+			{Href: self, Rel: "self", Type: "application/atom+xml"},
+			{Href: page, Rel: "alternate", Type: "text/html"},
+		},
+	}
+
+	var updated time.Time
+	posts := map[int64]string{}
+	for _, g := range groupDeliveries(deliveries) {
+		if g.updated.After(updated) {
+			updated = g.updated
+		}
+
+		entry := AtomEntry{
+			ID:      fmt.Sprintf("%s/notice/%d/%s", feedID, g.importID.Int64, g.date.Format(time.DateOnly)),
+			Title:   fmt.Sprintf("Entregas del %s (%d)", g.date.Format("02/01/2006"), len(g.deliveries)),
+			Updated: g.updated.UTC().Format(time.RFC3339),
+			Links:   []AtomLink{{Href: page, Rel: "alternate", Type: "text/html"}},
+			Content: AtomContent{Type: "html", Body: feedContent(g.deliveries)},
+		}
+		if g.importID.Valid {
+			postURL, err := s.postURL(r.Context(), posts, g.importID.Int64)
+			if err != nil {
+				return feed, err
+			}
+			if postURL != "" {
+				entry.Links = append(entry.Links, AtomLink{Href: postURL, Rel: "via"})
+			}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	// An empty feed was last updated... now.
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	feed.Updated = updated.UTC().Format(time.RFC3339)
+	return feed, nil
+}
+
+// feedTagAuthority is the tagging entity of the server: its host name,
+// without port, and FeedTagDate.
+func feedTagAuthority(r *http.Request) string {
+	host := r.Host
+	if u, err := url.Parse(baseURL(r)); err == nil {
+		host = u.Hostname()
+	}
+	return host + "," + FeedTagDate
+}
+
+// postURL returns the URL of an import's post, from cache when possible.
+func (s *Server) postURL(ctx context.Context, cache map[int64]string, importID int64) (string, error) {
+	if postURL, ok := cache[importID]; ok {
+		return postURL, nil
+	}
+	im, err := db.New(s.app.DB).GetImport(ctx, importID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+	cache[importID] = im.PostUrl
+	return im.PostUrl, nil
+}
+
+// groupDeliveries groups deliveries per import, and date, in order of
+// first appearance. A group is updated when its latest delivery was
+// created.
+func groupDeliveries(deliveries []db.Delivery) []*feedGroup {
+	type key struct {
+		importID sql.NullInt64
+		date     int64
+	}
+	var groups []*feedGroup
+	index := map[key]*feedGroup{}
+	for _, d := range deliveries {
+		k := key{d.ImportID, d.Date.Time.Unix()}
+		g, ok := index[k]
+		if !ok {
+			g = &feedGroup{importID: d.ImportID, date: d.Date.Time}
+			index[k] = g
+			groups = append(groups, g)
+		}
+		g.deliveries = append(g.deliveries, d)
+		if d.CreatedAt.Time.After(g.updated) {
+			g.updated = d.CreatedAt.Time
+		}
+	}
+	return groups
+}
+
+// feedContent lists deliveries in HTML.
+func feedContent(deliveries []db.Delivery) string {
+	var b strings.Builder
+	b.WriteString("<ul>")
+	for _, d := range deliveries {
+		b.WriteString("<li>")
+		b.WriteString(html.EscapeString(d.LocationType + " " + d.LocationName + ": " + d.Schedule))
+		if window := scheduleWindow(d); window != "" {
+			b.WriteString(", " + html.EscapeString(window))
+		}
+		if d.Notes != "" {
+			b.WriteString("<br />" + html.EscapeString(d.Notes))
+		}
+		b.WriteString("</li>")
+	}
+	b.WriteString("</ul>")
+	return b.String()
+}
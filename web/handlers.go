@@ -23,43 +23,67 @@ import (
 	"strings"
 
 	"git.cypr.io/oz/aguaxaca/app/db"
+	"git.cypr.io/oz/aguaxaca/normalizer"
 )
 
 func (s *Server) RootHandler(w http.ResponseWriter, r *http.Request) {
 	nameParam := r.URL.Query().Get("name")
-	deliveries, err := findDeliveries(r, s.app.DB, queryParamToFTS(nameParam))
+	deliveries, err := findDeliveries(r, s.app.DB, nameParam)
 	if err != nil {
 		s.app.Logger.Error("failed to list deliveries", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
+	slugs, err := locationSlugs(r, s.app.DB)
+	if err != nil {
+		s.app.Logger.Error("failed to list locations", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
 	// Render HTML.
-	err = s.templates.ExecuteTemplate(w, "index.html", map[string]any{
+	s.render(w, "index.html", map[string]any{
 		"Deliveries": deliveries,
 		"Name":       html.EscapeString(nameParam),
+		"Query":      nameParam, // raw, for URLs
+		"Slugs":      slugs,
 	})
-	if err != nil {
-		s.app.Logger.Error("failed to render template", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-	}
 }
 
 // findDeliveries for the home: either the latest, or FTS on name.
-func findDeliveries(r *http.Request, conn *sql.DB, nameSearch string) ([]db.Delivery, error) {
+func findDeliveries(r *http.Request, conn *sql.DB, nameParam string) ([]db.Delivery, error) {
 	queries := db.New(conn)
+	nameSearch := queryParamToFTS(nameParam)
 	if nameSearch == "" {
 		fromDate := db.UnixTime{Time: daysAgo(7)}
 		return queries.ListDeliveries(r.Context(), fromDate)
 	}
 
-	params := db.SearchDeliveriesByNameParams{
-		LocationName: nameSearch,
-		Date:         db.UnixTime{Time: daysAgo(90)},
-	}
+	params := db.SearchDeliveriesByNameParams{Date: db.UnixTime{Time: daysAgo(90)}}
+	params.LocationName, params.Sectors, params.Section = nameFilter(nameParam)
 	return queries.SearchDeliveriesByName(r.Context(), params)
 }
 
+// nameFilter splits a searched name into a FTS query on the base name,
+// and LIKE patterns on sectors and section: "sector 1" should not match
+// "sector 2, 1ª sección".
+func nameFilter(nameParam string) (fts string, sectors string, section string) {
+	fts, sectors, section = queryParamToFTS(nameParam), "%", "%"
+
+	name := normalizer.Parse(nameParam)
+	if base := queryParamToFTS(name.Base); base != "" {
+		fts = base
+		if len(name.Sectors) > 0 {
+			sectors = "%," + strings.Join(name.Sectors, ",%,") + ",%"
+		}
+		if name.Section != "" {
+			section = name.Section
+		}
+	}
+	return fts, sectors, section
+}
+
 // Basic search query param cleanup.
 func queryParamToFTS(param string) string {
 	trimmed := strings.ToLower(strings.TrimSpace(param))
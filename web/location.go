@@ -0,0 +1,87 @@
+// This file is part of Aguaxaca.
+// Copyright (C) 2025 Arnaud Berthomier.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or (at
+// your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the GNU
+// General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package web
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"git.cypr.io/oz/aguaxaca/app"
+	"git.cypr.io/oz/aguaxaca/app/db"
+)
+
+// LocationHandler renders the history of deliveries to a location.
+func (s *Server) LocationHandler(w http.ResponseWriter, r *http.Request) {
+	queries := db.New(s.app.DB)
+	location, err := queries.GetLocationBySlug(r.Context(), chi.URLParam(r, "slug"))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		s.app.Logger.Error("failed to get location", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// The zero date lists the whole history.
+	deliveries, err := queries.ListDeliveriesByLocation(r.Context(), db.ListDeliveriesByLocationParams{
+		LocationID: sql.NullInt64{Int64: location.ID, Valid: true},
+	})
+	if err != nil {
+		s.app.Logger.Error("failed to list deliveries", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// URLs of the source notices, per import.
+	notices := map[int64]string{}
+	for _, d := range deliveries {
+		if !d.ImportID.Valid {
+			continue
+		}
+		if _, err := s.postURL(r.Context(), notices, d.ImportID.Int64); err != nil {
+			s.app.Logger.Error("failed to get import", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	s.render(w, "location.html", map[string]any{
+		"Location": location,
+		"History":  app.NewHistory(deliveries),
+		"Notices":  notices,
+		"Today":    daysAgo(0),
+	})
+}
+
+// locationSlugs maps location IDs to their slugs, to link deliveries to
+// their location's page.
+func locationSlugs(r *http.Request, conn *sql.DB) (map[int64]string, error) {
+	locations, err := db.New(conn).ListLocations(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	slugs := make(map[int64]string, len(locations))
+	for _, l := range locations {
+		slugs[l.ID] = l.Slug
+	}
+	return slugs, nil
+}
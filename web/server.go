@@ -37,22 +37,34 @@ var content embed.FS
 // RequestTimeOut is 60 seconds
 const RequestTimeOut = 60
 
+// Pages are rendered with templates/layout.html.
+var pages = []string{
+	"index.html",
+	"location.html",
+	"admin_imports.html",
+	"admin_import.html",
+}
+
 type Server struct {
-	app       *app.App
-	templates *template.Template
-	server    *http.Server
+	app    *app.App
+	pages  map[string]*template.Template
+	server *http.Server
 }
 
 func NewServer(app *app.App) *Server {
-	tmpl := template.Must(
-		template.ParseFS(content,
-			"templates/layout.html",
-			"templates/index.html",
-		),
+	layout := template.Must(
+		template.New("").Funcs(templateFuncs).ParseFS(content, "templates/layout.html"),
 	)
+
+	// Each page gets its own copy of the layout, as they all define the
+	// same blocks.
+	tmpl := make(map[string]*template.Template, len(pages))
+	for _, page := range pages {
+		tmpl[page] = template.Must(template.Must(layout.Clone()).ParseFS(content, "templates/"+page))
+	}
 	return &Server{
-		app:       app,
-		templates: tmpl,
+		app:   app,
+		pages: tmpl,
 	}
 }
 
@@ -70,14 +82,29 @@ func (s *Server) NewHandler() http.Handler {
 
 	// Routes
 	r.Get("/", s.RootHandler)
+	r.Get("/colonia/{slug}", s.LocationHandler)
+	r.Get("/colonia/{slug}/calendar.ics", s.LocationCalendarHandler)
+	r.Get("/calendar.ics", s.SearchCalendarHandler)
+	r.Get("/feed.atom", s.FeedHandler)
+	r.Get("/location/{slug}/calendar.ics", s.LocationCalendarHandler) // same, for existing subscriptions
+	s.mountAPI(r)
+	s.mountAdmin(r)
 
 	return r
 }
 
+// render executes a page template, or responds with an error.
+func (s *Server) render(w http.ResponseWriter, page string, data any) {
+	if err := s.pages[page].ExecuteTemplate(w, page, data); err != nil {
+		s.app.Logger.Error("failed to render template", "page", page, "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
 // Run starts an http.Server
 func (s *Server) Run(_ context.Context) error {
-	s.app.Logger.Info("starting web server", "address", s.app.ListenAddr)
-	s.server = &http.Server{Addr: s.app.ListenAddr, Handler: s.NewHandler()}
+	s.app.Logger.Info("starting web server", "address", s.app.Config.ListenAddr)
+	s.server = &http.Server{Addr: s.app.Config.ListenAddr, Handler: s.NewHandler()}
 
 	err := s.server.ListenAndServe()
 	if err != http.ErrServerClosed {
@@ -118,10 +145,10 @@ func (s *Server) loggerOptions() *httplog.Options {
 		// LogRequestHeaders:  []string{"Origin"},
 		// LogResponseHeaders: []string{},
 
-		LogRequestBody:  isRequestLoggingEnabled(s.app.Debug),
-		LogResponseBody: isRequestLoggingEnabled(s.app.Debug),
+		LogRequestBody:  isRequestLoggingEnabled(s.app.Config.Debug),
+		LogResponseBody: isRequestLoggingEnabled(s.app.Config.Debug),
 	}
-	if s.app.Debug {
+	if s.app.Config.Debug {
 		opts.Level = slog.LevelDebug
 	}
 	return &opts
@@ -16,7 +16,13 @@
 
 package web
 
-import "time"
+import (
+	"net/http"
+	"time"
+
+	"git.cypr.io/oz/aguaxaca/app"
+	"git.cypr.io/oz/aguaxaca/app/db"
+)
 
 // Let's compute Oaxaca's zone offset just once.
 var utcMinus6 = time.FixedZone("UTC-6", -6*60*60)
@@ -26,3 +32,27 @@ func daysAgo(n int) time.Time {
 	now := time.Now().In(utcMinus6)
 	return now.AddDate(0, 0, -n)
 }
+
+// baseURL is the scheme, and host of the server, as seen by the client:
+// behind a proxy, X-Forwarded-Proto tells whether it uses HTTPS.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// Functions available in templates.
+var templateFuncs = map[string]any{
+	"window": scheduleWindow,
+}
+
+// scheduleWindow describes when a delivery happens: "entre 06:00 y
+// 18:00", or nothing when unknown.
+func scheduleWindow(d db.Delivery) string {
+	if s := app.DeliverySchedule(d); s.Window != nil {
+		return s.Window.String()
+	}
+	return ""
+}
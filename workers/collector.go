@@ -25,11 +25,7 @@ import (
 	"git.cypr.io/oz/aguaxaca/app/db"
 )
 
-// CollectorGracePeriod is the number of hours we wait after the last
-// successful import, before checking for new reports.
-const CollectorGracePeriod = 12
-
-// An hourly job to run app.DefaultCollector.
+// A periodic job to run app.DefaultCollector.
 func collectorJob(app *app.App) error {
 	log := app.Logger.With("job", "collector")
 	if !shouldRunCollector(app, log) {
@@ -43,7 +39,7 @@ func collectorJob(app *app.App) error {
 	}
 
 	// Parse new reports.
-	analyzer := app.NewAnalyzer()
+	analyzer := app.NewAnalyzer(app.DefaultParser())
 	count, err := analyzer.ProcessPendingImports()
 	if err != nil {
 		log.Error("Collect", "ProcessPendingImports", err)
@@ -55,8 +51,8 @@ func collectorJob(app *app.App) error {
 }
 
 // If the latest import was completed less than CollectorGracePeriod
-// (12) hours ago, we don't need to check for a new report yet.
-// After that, run on the hour to look for new data.
+// (12 hours by default) ago, we don't need to check for a new report
+// yet. After that, run every CollectInterval to look for new data.
 func shouldRunCollector(app *app.App, log *slog.Logger) bool {
 	queries := db.New(app.DB)
 	latest, err := queries.GetLatestImport(app.Ctx)
@@ -68,7 +64,7 @@ func shouldRunCollector(app *app.App, log *slog.Logger) bool {
 		return false
 	}
 
-	nextRunTime := latest.CreatedAt.Time.Add(time.Duration(CollectorGracePeriod * time.Hour))
+	nextRunTime := latest.CreatedAt.Time.Add(app.Config.CollectorGracePeriod)
 	now := time.Now().UTC()
 	return nextRunTime.Before(now)
 }
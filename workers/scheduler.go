@@ -18,7 +18,6 @@ package workers
 
 import (
 	"context"
-	"time"
 
 	"git.cypr.io/oz/aguaxaca/app"
 	"github.com/go-co-op/gocron/v2"
@@ -36,9 +35,9 @@ func NewScheduler(app *app.App) *Scheduler {
 		return nil
 	}
 
-	// Run collectorJob hourly.
+	// Run collectorJob every CollectInterval (hourly by default).
 	if _, err = sched.NewJob(
-		gocron.DurationJob(1*time.Hour),
+		gocron.DurationJob(app.Config.CollectInterval),
 		gocron.NewTask(collectorJob, app),
 		gocron.WithSingletonMode(gocron.LimitModeReschedule),
 	); err != nil {